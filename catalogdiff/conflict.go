@@ -0,0 +1,146 @@
+package catalogdiff
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// OperationConflict represents several operations targeting the same node or service
+type OperationConflict struct {
+	Kind        string // "node", "service" or "check"
+	Target      string
+	Lines       []int // Source lines of every operation on the target, in order
+	Conflicting bool  // True if the operations disagree, false for plain duplicates
+	Reason      string
+}
+
+// OperationTarget returns the kind and key of the target an operation applies to
+func OperationTarget(op Operation) (string, string) {
+	switch {
+	case op.Node != nil:
+		nodeName, _ := extractNodeInfo(op.Node.Node)
+		return "node", nodeName
+	case op.Service != nil:
+		nodeName, serviceID, _ := extractServiceInfo(op.Service)
+		return "service", fmt.Sprintf("%s/%s", nodeName, serviceID)
+	case op.Check != nil:
+		checkID, _ := op.Check.Check["CheckID"].(string)
+		if checkID == "" {
+			checkID, _ = op.Check.Check["Name"].(string)
+		}
+		return "check", fmt.Sprintf("%s/%s", op.Check.Node, checkID)
+	default:
+		return "", ""
+	}
+}
+
+// ResolveOperations applies "last operation wins" to write operations
+// sharing a target. The effective operation keeps the position of the last
+// occurrence, so the result is deterministic for a given input. Read-only
+// operations, such as get, are kept and never replace a write.
+func ResolveOperations(operations []Operation) ([]Operation, []OperationConflict) {
+	groups := make(map[string][]int)
+	var order []string
+
+	for i, op := range operations {
+		kind, target := OperationTarget(op)
+		if verb, _ := operationVerbAndPayload(op); kind == "" || !isWriteVerb(verb) {
+			continue
+		}
+		key := kind + ":" + target
+		if _, seen := groups[key]; !seen {
+			order = append(order, key)
+		}
+		groups[key] = append(groups[key], i)
+	}
+
+	superseded := make(map[int]bool)
+	var conflicts []OperationConflict
+	for _, key := range order {
+		indexes := groups[key]
+		if len(indexes) < 2 {
+			continue
+		}
+		for _, i := range indexes[:len(indexes)-1] {
+			superseded[i] = true
+		}
+		conflicts = append(conflicts, describeConflict(operations, indexes))
+	}
+
+	effective := make([]Operation, 0, len(operations)-len(superseded))
+	for i, op := range operations {
+		if !superseded[i] {
+			effective = append(effective, op)
+		}
+	}
+
+	return effective, conflicts
+}
+
+// describeConflict classifies operations on the same target as duplicates or conflicts
+func describeConflict(operations []Operation, indexes []int) OperationConflict {
+	first := operations[indexes[0]]
+	kind, target := OperationTarget(first)
+
+	conflict := OperationConflict{
+		Kind:   kind,
+		Target: target,
+		Reason: "duplicate operations",
+	}
+
+	for _, i := range indexes {
+		conflict.Lines = append(conflict.Lines, operations[i].Line)
+	}
+
+	firstVerb, firstPayload := operationVerbAndPayload(first)
+	for _, i := range indexes[1:] {
+		verb, payload := operationVerbAndPayload(operations[i])
+		switch {
+		case verbClass(verb) != verbClass(firstVerb):
+			conflict.Conflicting = true
+			conflict.Reason = fmt.Sprintf("conflicting verbs %q and %q", firstVerb, verb)
+			return conflict
+		case verbClass(verb) == "set" && !reflect.DeepEqual(normalizeMap(payload), normalizeMap(firstPayload)):
+			conflict.Conflicting = true
+			conflict.Reason = "set operations with different values"
+			return conflict
+		}
+	}
+
+	return conflict
+}
+
+// operationVerbAndPayload returns the verb and payload of an operation
+func operationVerbAndPayload(op Operation) (string, map[string]interface{}) {
+	switch {
+	case op.Node != nil:
+		return op.Node.Verb, op.Node.Node
+	case op.Service != nil:
+		return op.Service.Verb, op.Service.Service
+	case op.Check != nil:
+		return op.Check.Verb, op.Check.Check
+	default:
+		return "", nil
+	}
+}
+
+// isWriteVerb reports whether a verb changes its target
+func isWriteVerb(verb string) bool {
+	switch verbClass(verb) {
+	case "set", "delete":
+		return true
+	}
+	return false
+}
+
+// verbClass groups verbs that have the same effect on the target
+func verbClass(verb string) string {
+	switch verb {
+	case "set", "cas":
+		return "set"
+	case "delete", "delete-cas":
+		return "delete"
+	default:
+		return verb
+	}
+}
@@ -0,0 +1,82 @@
+package catalogdiff
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Consistency modes of catalog reads
+const (
+	ConsistencyDefault    = "default"    // Served by the leader, which may briefly be stale
+	ConsistencyStale      = "stale"      // Served by any server, possibly lagging behind
+	ConsistencyConsistent = "consistent" // Leadership is verified before every read
+)
+
+// ParseConsistency validates a consistency mode, where "" means the default
+func ParseConsistency(mode string) (string, error) {
+	switch mode {
+	case "", ConsistencyDefault:
+		return ConsistencyDefault, nil
+	case ConsistencyStale, ConsistencyConsistent:
+		return mode, nil
+	}
+	return "", fmt.Errorf("unknown consistency mode %q, want stale, default or consistent", mode)
+}
+
+// ReadStats describes the consistency of the responses read by a ConsulSource
+type ReadStats struct {
+	Consistency    string
+	Responses      int           // Responses carrying the X-Consul-LastContact header
+	MaxLastContact time.Duration // Largest X-Consul-LastContact, the lag behind the leader
+	NoKnownLeader  bool          // Some response had X-Consul-KnownLeader: false
+	Lagging        bool          // MaxLastContact exceeds the source's MaxStaleLag
+	MaxStaleLag    time.Duration
+}
+
+// ReadStats returns the consistency of the responses read so far
+func (s *ConsulSource) ReadStats() ReadStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := s.stats
+	stats.Consistency, _ = ParseConsistency(s.Consistency)
+	stats.MaxStaleLag = s.MaxStaleLag
+	stats.Lagging = s.MaxStaleLag > 0 && stats.MaxLastContact > s.MaxStaleLag
+	return stats
+}
+
+// withConsistency adds the query parameter of the consistency mode to a
+// URL, failing on unknown modes rather than reading in the default mode
+func (s *ConsulSource) withConsistency(u *url.URL) error {
+	mode, err := ParseConsistency(s.Consistency)
+	if err != nil {
+		return err
+	}
+	if mode != ConsistencyDefault {
+		if u.RawQuery != "" {
+			u.RawQuery += "&"
+		}
+		u.RawQuery += mode
+	}
+	return nil
+}
+
+// observe records the consistency headers of a response
+func (s *ConsulSource) observe(resp *http.Response) {
+	lastContact := resp.Header.Get("X-Consul-LastContact")
+	knownLeader := resp.Header.Get("X-Consul-KnownLeader")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if ms, err := strconv.ParseInt(lastContact, 10, 64); err == nil {
+		s.stats.Responses++
+		s.stats.MaxLastContact = max(s.stats.MaxLastContact, time.Duration(ms)*time.Millisecond)
+	}
+	if knownLeader == "false" {
+		s.stats.NoKnownLeader = true
+	}
+}
@@ -0,0 +1,136 @@
+package catalogdiff
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestConsulSourceConsistency(t *testing.T) {
+	var mu sync.Mutex
+	var queries []string
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/catalog/nodes", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		queries = append(queries, r.URL.RawQuery)
+		mu.Unlock()
+		w.Header().Set("X-Consul-LastContact", "40")
+		w.Header().Set("X-Consul-KnownLeader", "true")
+		w.Write([]byte(`[{"Node":"web-001","Address":"10.0.0.1"}]`))
+	})
+	mux.HandleFunc("/v1/catalog/node/", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		queries = append(queries, r.URL.RawQuery)
+		mu.Unlock()
+		w.Header().Set("X-Consul-LastContact", "7000")
+		w.Header().Set("X-Consul-KnownLeader", "false")
+		w.Write([]byte(`{"Node":{"Node":"web-001"},"Services":{}}`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	operations := []Operation{
+		{Node: &NodeOperation{Verb: "set", Node: map[string]interface{}{"Node": "web-001", "Address": "10.0.0.1"}}},
+		{Service: &ServiceOperation{Verb: "set", Node: "web-001", Service: map[string]interface{}{"ID": "nginx"}}},
+	}
+
+	tests := []struct {
+		name        string
+		consistency string
+		maxStaleLag time.Duration
+		wantQuery   string
+		wantMode    string
+		wantLagging bool
+	}{
+		{name: "unset", wantQuery: "", wantMode: ConsistencyDefault},
+		{name: "default", consistency: ConsistencyDefault, wantQuery: "", wantMode: ConsistencyDefault},
+		{name: "stale", consistency: ConsistencyStale, maxStaleLag: 5 * time.Second, wantQuery: "stale", wantMode: ConsistencyStale, wantLagging: true},
+		{name: "stale within threshold", consistency: ConsistencyStale, maxStaleLag: 10 * time.Second, wantQuery: "stale", wantMode: ConsistencyStale},
+		{name: "consistent", consistency: ConsistencyConsistent, wantQuery: "consistent", wantMode: ConsistencyConsistent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			queries = nil
+
+			src := NewConsulSource(server.URL)
+			src.Consistency = tt.consistency
+			src.MaxStaleLag = tt.maxStaleLag
+
+			diff, err := Diff(context.Background(), operations, src, DiffOptions{})
+			if err != nil {
+				t.Fatalf("Diff() error = %v", err)
+			}
+
+			if len(queries) != 2 {
+				t.Fatalf("got %d requests, want 2", len(queries))
+			}
+			for _, q := range queries {
+				if q != tt.wantQuery {
+					t.Errorf("query = %q, want %q", q, tt.wantQuery)
+				}
+			}
+
+			want := ReadStats{
+				Consistency:    tt.wantMode,
+				Responses:      2,
+				MaxLastContact: 7 * time.Second,
+				NoKnownLeader:  true,
+				Lagging:        tt.wantLagging,
+				MaxStaleLag:    tt.maxStaleLag,
+			}
+			if diff.Reads == nil || *diff.Reads != want {
+				t.Errorf("Reads = %+v, want %+v", diff.Reads, want)
+			}
+		})
+	}
+}
+
+func TestConsulSourceUnknownConsistency(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte(`[]`))
+	}))
+	defer server.Close()
+
+	operations := []Operation{
+		{Node: &NodeOperation{Verb: "set", Node: map[string]interface{}{"Node": "web-001"}}},
+	}
+
+	src := NewConsulSource(server.URL)
+	src.Consistency = "Stale"
+	_, err := src.FetchState(context.Background(), operations)
+	if err == nil || !strings.Contains(err.Error(), `unknown consistency mode "Stale"`) {
+		t.Errorf("FetchState() error = %v, want an unknown consistency mode error", err)
+	}
+	if requests != 0 {
+		t.Errorf("got %d requests, want none", requests)
+	}
+}
+
+func TestParseConsistency(t *testing.T) {
+	tests := []struct {
+		mode    string
+		want    string
+		wantErr bool
+	}{
+		{mode: "", want: ConsistencyDefault},
+		{mode: "default", want: ConsistencyDefault},
+		{mode: "stale", want: ConsistencyStale},
+		{mode: "consistent", want: ConsistencyConsistent},
+		{mode: "eventual", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseConsistency(tt.mode)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseConsistency(%q) = %q, %v, want %q, error %v", tt.mode, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,312 @@
+package catalogdiff
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ConsulSource fetches the current state from the Consul HTTP API
+type ConsulSource struct {
+	Addr        string // Consul HTTP address, e.g. "http://127.0.0.1:8500"
+	Client      *http.Client
+	Retry       RetryPolicy
+	Consistency string        // Consistency mode of every read, ConsistencyDefault if ""
+	MaxStaleLag time.Duration // Lag behind the leader reported as Lagging by ReadStats; 0 disables
+	Logger      *log.Logger   // Receives progress and retry messages; nil discards them
+
+	mu    sync.Mutex
+	stats ReadStats
+}
+
+// NewConsulSource creates a ConsulSource for a Consul HTTP address, with a
+// 30 second timeout per request
+func NewConsulSource(addr string) *ConsulSource {
+	return &ConsulSource{
+		Addr: addr,
+		Client: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+	}
+}
+
+// FetchState fetches the nodes, services and checks referenced by operations.
+// When ctx is canceled, the error reports how many requests had completed.
+func (s *ConsulSource) FetchState(ctx context.Context, operations []Operation) (*ConsulState, error) {
+	state := &ConsulState{
+		Nodes:    make(map[string]ConsulNode),
+		Services: make(map[string][]ConsulService),
+		Checks:   make(map[string][]ConsulCheck),
+	}
+
+	// Group operations by target to minimize API calls
+	nodeOps, serviceOps := groupOperationsByTarget(operations)
+
+	// Services are fetched for the nodes of service operations, and for
+	// nodes to be deleted since their deletion cascades to the services
+	serviceNodes := make(map[string]bool)
+	for key := range serviceOps {
+		serviceNodes[getNodeFromServiceKey(key)] = true
+	}
+	var deletedNodes []string
+	for nodeName, nodeOp := range nodeOps {
+		if verbClass(nodeOp.Verb) == "delete" {
+			serviceNodes[nodeName] = true
+			deletedNodes = append(deletedNodes, nodeName)
+		}
+	}
+
+	progress := fetchProgress{total: len(nodeOps) + len(serviceNodes) + len(deletedNodes)}
+
+	// Fetch nodes that are referenced in operations
+	for nodeName := range nodeOps {
+		logf(s.Logger, "[INFO] Fetching node: %s", nodeName)
+		node, err := s.fetchNode(ctx, nodeName)
+		if err != nil {
+			if isNotFoundError(err) {
+				logf(s.Logger, "[INFO] Node %s not found in Consul", nodeName)
+				progress.done++
+				continue
+			}
+			return nil, progress.wrap(ctx, fmt.Errorf("failed to fetch node %s: %w", nodeName, err))
+		}
+		state.Nodes[nodeName] = *node
+		progress.done++
+	}
+
+	for nodeName := range serviceNodes {
+		logf(s.Logger, "[INFO] Fetching services for node: %s", nodeName)
+		services, err := s.fetchNodeServices(ctx, nodeName)
+		if err != nil {
+			if isNotFoundError(err) {
+				logf(s.Logger, "[INFO] Node %s not found in Consul", nodeName)
+				progress.done++
+				continue
+			}
+			return nil, progress.wrap(ctx, fmt.Errorf("failed to fetch services for node %s: %w", nodeName, err))
+		}
+		state.Services[nodeName] = services
+		progress.done++
+	}
+
+	// Fetch checks of nodes to be deleted
+	for _, nodeName := range deletedNodes {
+		logf(s.Logger, "[INFO] Fetching checks for node: %s", nodeName)
+		checks, err := s.fetchNodeChecks(ctx, nodeName)
+		if err != nil {
+			return nil, progress.wrap(ctx, fmt.Errorf("failed to fetch checks for node %s: %w", nodeName, err))
+		}
+		state.Checks[nodeName] = checks
+		progress.done++
+	}
+
+	return state, nil
+}
+
+// fetchProgress counts the completed requests of FetchState
+type fetchProgress struct {
+	done  int
+	total int
+}
+
+// wrap adds the progress to an error caused by a canceled or expired context
+func (p fetchProgress) wrap(ctx context.Context, err error) error {
+	if ctx.Err() == nil {
+		return err
+	}
+	return fmt.Errorf("%w (stopped after %d of %d requests)", err, p.done, p.total)
+}
+
+// NodeExists checks if a node is registered in Consul
+func (s *ConsulSource) NodeExists(ctx context.Context, nodeName string) (bool, error) {
+	_, err := s.fetchNode(ctx, nodeName)
+	if isNotFoundError(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// fetchNode fetches a single node from Consul
+func (s *ConsulSource) fetchNode(ctx context.Context, nodeName string) (*ConsulNode, error) {
+	nodes, err := s.fetchNodes(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Find the specific node
+	for _, node := range nodes {
+		if node.Node == nodeName {
+			return &node, nil
+		}
+	}
+
+	return nil, &notFoundError{resource: "node", name: nodeName}
+}
+
+// fetchNodes fetches all nodes from Consul
+func (s *ConsulSource) fetchNodes(ctx context.Context) ([]ConsulNode, error) {
+	u, err := url.Parse(fmt.Sprintf("%s/v1/catalog/nodes", s.Addr))
+	if err != nil {
+		return nil, fmt.Errorf("invalid consul address: %w", err)
+	}
+
+	resp, err := s.get(ctx, u.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch nodes: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("consul returned status %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	var nodes []ConsulNode
+	if err := json.Unmarshal(body, &nodes); err != nil {
+		return nil, fmt.Errorf("failed to parse nodes: %w", err)
+	}
+
+	return nodes, nil
+}
+
+// fetchNodeServices fetches services for a specific node
+func (s *ConsulSource) fetchNodeServices(ctx context.Context, nodeName string) ([]ConsulService, error) {
+	u, err := url.Parse(fmt.Sprintf("%s/v1/catalog/node/%s", s.Addr, nodeName))
+	if err != nil {
+		return nil, fmt.Errorf("invalid consul address: %w", err)
+	}
+
+	resp, err := s.get(ctx, u.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch node services: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, &notFoundError{resource: "node", name: nodeName}
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("consul returned status %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	var nodeData *struct {
+		Services map[string]ConsulService `json:"Services"`
+	}
+
+	if err := json.Unmarshal(body, &nodeData); err != nil {
+		return nil, fmt.Errorf("failed to parse node services: %w", err)
+	}
+
+	// Consul answers with null for unknown nodes
+	if nodeData == nil {
+		return nil, &notFoundError{resource: "node", name: nodeName}
+	}
+
+	// Convert map to slice, ordered by ID for deterministic output
+	services := []ConsulService{}
+	for _, svc := range nodeData.Services {
+		services = append(services, svc)
+	}
+	sort.Slice(services, func(i, j int) bool {
+		return services[i].ID < services[j].ID
+	})
+
+	return services, nil
+}
+
+// fetchNodeChecks fetches health checks for a specific node
+func (s *ConsulSource) fetchNodeChecks(ctx context.Context, nodeName string) ([]ConsulCheck, error) {
+	u, err := url.Parse(fmt.Sprintf("%s/v1/health/node/%s", s.Addr, nodeName))
+	if err != nil {
+		return nil, fmt.Errorf("invalid consul address: %w", err)
+	}
+
+	resp, err := s.get(ctx, u.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch node checks: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("consul returned status %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	var checks []ConsulCheck
+	if err := json.Unmarshal(body, &checks); err != nil {
+		return nil, fmt.Errorf("failed to parse node checks: %w", err)
+	}
+
+	return checks, nil
+}
+
+// get sends a GET request to Consul in the consistency mode of the source,
+// retrying transient failures
+func (s *ConsulSource) get(ctx context.Context, rawURL string) (*http.Response, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.withConsistency(u); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.doWithRetry(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	s.observe(resp)
+	return resp, nil
+}
+
+// getNodeFromServiceKey extracts node name from service key
+func getNodeFromServiceKey(key string) string {
+	idx := strings.Index(key, "/")
+	if idx > 0 {
+		return key[:idx]
+	}
+	return key
+}
+
+// notFoundError represents a resource not found error
+type notFoundError struct {
+	resource string
+	name     string
+}
+
+func (e *notFoundError) Error() string {
+	return fmt.Sprintf("%s %s not found", e.resource, e.name)
+}
+
+// isNotFoundError checks if an error is a not found error
+func isNotFoundError(err error) bool {
+	_, ok := err.(*notFoundError)
+	return ok
+}
@@ -0,0 +1,142 @@
+package catalogdiff
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestConsul starts a fake Consul serving web-001 with an nginx service
+func newTestConsul(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/catalog/nodes", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"Node":"web-001","Address":"10.0.0.1","Datacenter":"dc1"}]`))
+	})
+	mux.HandleFunc("/v1/catalog/node/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/catalog/node/web-001" {
+			w.Write([]byte(`null`))
+			return
+		}
+		w.Write([]byte(`{"Node":{"Node":"web-001"},"Services":{"nginx":{"ID":"nginx","Service":"nginx","Port":80}}}`))
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestDiffConsulSource(t *testing.T) {
+	server := newTestConsul(t)
+
+	operations := []Operation{
+		{Node: &NodeOperation{Verb: "set", Node: map[string]interface{}{"Node": "web-001", "Address": "10.0.0.2"}}},
+		{Service: &ServiceOperation{Verb: "set", Node: "web-001", Service: map[string]interface{}{"ID": "nginx", "Port": float64(80)}}},
+		{Service: &ServiceOperation{Verb: "set", Node: "web-002", Service: map[string]interface{}{"ID": "redis"}}},
+	}
+
+	diff, err := Diff(context.Background(), operations, NewConsulSource(server.URL), DiffOptions{})
+	if err != nil {
+		t.Fatalf("Diff() error = %v", err)
+	}
+
+	if len(diff.NodeModifications) != 1 || diff.NodeModifications[0].Fields[0].Field != "Address" {
+		t.Errorf("NodeModifications = %+v, want an Address change on web-001", diff.NodeModifications)
+	}
+	if len(diff.ServiceModifications) != 0 {
+		t.Errorf("ServiceModifications = %+v, want none", diff.ServiceModifications)
+	}
+	if len(diff.ServiceAdditions) != 1 || !diff.ServiceAdditions[0].Orphan {
+		t.Errorf("ServiceAdditions = %+v, want orphan web-002/redis", diff.ServiceAdditions)
+	}
+}
+
+func TestDiffConsulSourceError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	operations := []Operation{
+		{Node: &NodeOperation{Verb: "set", Node: map[string]interface{}{"Node": "web-001"}}},
+	}
+	if _, err := Diff(context.Background(), operations, NewConsulSource(server.URL), DiffOptions{}); err == nil {
+		t.Error("Diff() succeeded, want error")
+	}
+}
+
+func TestConsulSourceCanceled(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/catalog/nodes", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"Node":"web-001","Address":"10.0.0.1"}]`))
+	})
+	mux.HandleFunc("/v1/catalog/node/", func(w http.ResponseWriter, r *http.Request) {
+		// Hang until the client gives up
+		<-r.Context().Done()
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	operations := []Operation{
+		{Node: &NodeOperation{Verb: "set", Node: map[string]interface{}{"Node": "web-001"}}},
+		{Service: &ServiceOperation{Verb: "set", Node: "web-001", Service: map[string]interface{}{"ID": "nginx"}}},
+	}
+
+	t.Run("deadline", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+
+		_, err := NewConsulSource(server.URL).FetchState(ctx, operations)
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("FetchState() error = %v, want context.DeadlineExceeded", err)
+		}
+		if !strings.Contains(err.Error(), "stopped after 1 of 2 requests") {
+			t.Errorf("FetchState() error = %v, want the progress", err)
+		}
+	})
+
+	t.Run("request timeout", func(t *testing.T) {
+		src := NewConsulSource(server.URL)
+		src.Client.Timeout = 100 * time.Millisecond
+
+		_, err := src.FetchState(context.Background(), operations)
+		if err == nil || strings.Contains(err.Error(), "stopped after") {
+			t.Errorf("FetchState() error = %v, want a timeout without progress", err)
+		}
+	})
+}
+
+func TestValidateNodeReferences(t *testing.T) {
+	server := newTestConsul(t)
+
+	input := `{"Node":{"Verb":"delete","Node":{"Node":"web-001"}}}
+{"Service":{"Verb":"set","Node":"web-001","Service":{"ID":"nginx"}}}
+{"Service":{"Verb":"delete","Node":"web-001","Service":{"ID":"redis"}}}
+{"Node":{"Verb":"set","Node":{"Node":"web-002"}}}
+{"Service":{"Verb":"set","Node":"web-002","Service":{"ID":"nginx"}}}
+{"Service":{"Verb":"set","Node":"web-003","Service":{"ID":"nginx"}}}`
+
+	ops, err := parseNDJSON([]byte(input))
+	if err != nil {
+		t.Fatalf("parseNDJSON() error = %v", err)
+	}
+
+	violations, err := ValidateNodeReferences(context.Background(), NewConsulSource(server.URL), ops)
+	if err != nil {
+		t.Fatalf("ValidateNodeReferences() error = %v", err)
+	}
+
+	want := []Violation{
+		{Line: 2, Target: "service web-001/nginx", Message: "node web-001 is deleted by the payload at line 1"},
+		{Line: 6, Target: "service web-003/nginx", Message: "node web-003 is neither defined in the payload nor registered in Consul"},
+	}
+	if !reflect.DeepEqual(violations, want) {
+		t.Errorf("ValidateNodeReferences() =\n%v\nwant\n%v", violations, want)
+	}
+}
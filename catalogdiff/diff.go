@@ -0,0 +1,273 @@
+package catalogdiff
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Diff fetches the current state of the targets of operations from src and
+// calculates the differences to the operations
+func Diff(ctx context.Context, operations []Operation, src StateSource, opts DiffOptions) (*DiffResult, error) {
+	state, err := src.FetchState(ctx, operations)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch current state: %w", err)
+	}
+	if opts.StateFetched != nil {
+		if err := opts.StateFetched(state); err != nil {
+			return nil, err
+		}
+	}
+
+	result, err := Calculate(ctx, operations, state, opts)
+	if err != nil {
+		return nil, err
+	}
+	if consul, ok := src.(*ConsulSource); ok {
+		stats := consul.ReadStats()
+		result.Reads = &stats
+	}
+	return result, nil
+}
+
+// Calculate calculates differences between expected operations and current
+// state, stopping with the context's error once ctx is done
+func Calculate(ctx context.Context, operations []Operation, currentState *ConsulState, opts DiffOptions) (*DiffResult, error) {
+	result := &DiffResult{}
+
+	// Resolve operations sharing a target; the last one wins
+	effective, conflicts := ResolveOperations(operations)
+	for _, c := range conflicts {
+		logf(opts.Logger, "[WARN] %s %s: %s at lines %s, using line %d", c.Kind, c.Target, c.Reason, formatLines(c.Lines), c.Lines[len(c.Lines)-1])
+	}
+	result.Conflicts = conflicts
+
+	// Process each operation
+	for _, op := range effective {
+		if err := ctx.Err(); err != nil {
+			return nil, fmt.Errorf("diff interrupted: %w", err)
+		}
+		if op.Node != nil {
+			processNodeOperation(op.Node, op.Line, currentState, opts, result)
+		}
+		if op.Service != nil {
+			processServiceOperation(op.Service, op.Line, currentState, opts, result)
+		}
+		// Note: Check operations not implemented yet
+	}
+
+	// Flag services added to nodes that are neither registered nor defined
+	markOrphanServices(effective, currentState, result)
+
+	// Deleting a node also deregisters its services and checks
+	for _, del := range result.NodeDeletions {
+		if err := ctx.Err(); err != nil {
+			return nil, fmt.Errorf("diff interrupted: %w", err)
+		}
+		cascadeNodeDeletion(del, effective, currentState, result)
+	}
+
+	// Set aside differences matched by ignore rules
+	applyIgnoreRules(result, opts.Ignore)
+
+	return result, nil
+}
+
+// markOrphanServices flags service additions whose node exists neither in
+// Consul nor as a node set operation in the payload
+func markOrphanServices(operations []Operation, state *ConsulState, result *DiffResult) {
+	defined := make(map[string]bool)
+	for _, op := range operations {
+		if op.Node != nil && verbClass(op.Node.Verb) == "set" {
+			nodeName, _ := extractNodeInfo(op.Node.Node)
+			defined[nodeName] = true
+		}
+	}
+
+	for i := range result.ServiceAdditions {
+		add := &result.ServiceAdditions[i]
+		_, hasNode := state.Nodes[add.Node]
+		_, hasServices := state.Services[add.Node]
+		if !hasNode && !hasServices && !defined[add.Node] {
+			add.Orphan = true
+		}
+	}
+}
+
+// cascadeNodeDeletion records the services and checks of a deleted node as
+// implied deletions, skipping services the payload has operations for since
+// those operations report them
+func cascadeNodeDeletion(node NodeDiff, operations []Operation, state *ConsulState, result *DiffResult) {
+	nodeName := node.Node
+	handled := make(map[string]bool)
+	for _, op := range operations {
+		if op.Service == nil {
+			continue
+		}
+		if opNode, serviceID, _ := extractServiceInfo(op.Service); opNode == nodeName {
+			handled[serviceID] = true
+		}
+	}
+
+	for i := range state.Services[nodeName] {
+		svc := state.Services[nodeName][i]
+		if handled[svc.ID] {
+			continue
+		}
+		result.ServiceDeletions = append(result.ServiceDeletions, ServiceDiff{
+			Node:      nodeName,
+			ServiceID: svc.ID,
+			Current:   &svc,
+			Cascaded:  true,
+			Line:      node.Line,
+		})
+	}
+
+	for i := range state.Checks[nodeName] {
+		check := state.Checks[nodeName][i]
+		result.CheckDeletions = append(result.CheckDeletions, CheckDiff{
+			Node:     nodeName,
+			CheckID:  check.CheckID,
+			Current:  &check,
+			Cascaded: true,
+			Line:     node.Line,
+		})
+	}
+}
+
+// processNodeOperation processes a single node operation
+func processNodeOperation(nodeOp *NodeOperation, line int, state *ConsulState, opts DiffOptions, result *DiffResult) {
+	nodeName, nodeData := extractNodeInfo(nodeOp.Node)
+	if nodeName == "" {
+		logf(opts.Logger, "[WARN] Node operation missing node name")
+		return
+	}
+
+	currentNode, exists := state.Nodes[nodeName]
+
+	switch verbClass(nodeOp.Verb) {
+	case "set":
+		if !exists {
+			// Node doesn't exist - addition
+			result.NodeAdditions = append(result.NodeAdditions, NodeDiff{
+				Node:     nodeName,
+				Expected: nodeData,
+				Current:  nil,
+				Line:     line,
+			})
+		} else {
+			// Node exists - check for modifications
+			diffs := compareNodeFields(nodeData, currentNode, opts)
+			if len(diffs) > 0 {
+				result.NodeModifications = append(result.NodeModifications, NodeDiff{
+					Node:     nodeName,
+					Expected: nodeData,
+					Current:  &currentNode,
+					Fields:   diffs,
+					Line:     line,
+				})
+			}
+		}
+
+	case "delete":
+		if exists {
+			// Node exists and should be deleted
+			result.NodeDeletions = append(result.NodeDeletions, NodeDiff{
+				Node:     nodeName,
+				Expected: nodeData,
+				Current:  &currentNode,
+				Line:     line,
+			})
+		}
+		// If node doesn't exist, nothing to delete (already in desired state)
+	}
+}
+
+// processServiceOperation processes a single service operation
+func processServiceOperation(serviceOp *ServiceOperation, line int, state *ConsulState, opts DiffOptions, result *DiffResult) {
+	nodeName, serviceID, serviceData := extractServiceInfo(serviceOp)
+	if nodeName == "" || serviceID == "" {
+		logf(opts.Logger, "[WARN] Service operation missing node name or service ID")
+		return
+	}
+
+	// Find current service
+	currentService := findCurrentService(state, nodeName, serviceID)
+
+	switch verbClass(serviceOp.Verb) {
+	case "set":
+		processServiceSetOperation(currentService, nodeName, serviceID, serviceData, line, opts, result)
+	case "delete":
+		processServiceDeleteOperation(currentService, nodeName, serviceID, serviceData, line, result)
+	}
+}
+
+// findCurrentService finds a service in the current state
+func findCurrentService(state *ConsulState, nodeName, serviceID string) *ConsulService {
+	services, ok := state.Services[nodeName]
+	if !ok {
+		return nil
+	}
+
+	for _, svc := range services {
+		if svc.ID == serviceID {
+			return &svc
+		}
+	}
+
+	return nil
+}
+
+// processServiceSetOperation processes set/cas operations for services
+func processServiceSetOperation(currentService *ConsulService, nodeName, serviceID string, serviceData map[string]interface{}, line int, opts DiffOptions, result *DiffResult) {
+	if currentService == nil {
+		// Service doesn't exist - addition
+		result.ServiceAdditions = append(result.ServiceAdditions, ServiceDiff{
+			Node:      nodeName,
+			ServiceID: serviceID,
+			Expected:  serviceData,
+			Current:   nil,
+			Line:      line,
+		})
+		return
+	}
+
+	// Service exists - check for modifications
+	diffs := compareServiceFields(serviceData, *currentService, opts)
+	if len(diffs) > 0 {
+		result.ServiceModifications = append(result.ServiceModifications, ServiceDiff{
+			Node:      nodeName,
+			ServiceID: serviceID,
+			Expected:  serviceData,
+			Current:   currentService,
+			Fields:    diffs,
+			Line:      line,
+		})
+	}
+}
+
+// processServiceDeleteOperation processes delete operations for services
+func processServiceDeleteOperation(currentService *ConsulService, nodeName, serviceID string, serviceData map[string]interface{}, line int, result *DiffResult) {
+	if currentService == nil {
+		// Service doesn't exist, nothing to delete (already in desired state)
+		return
+	}
+
+	// Service exists and should be deleted
+	result.ServiceDeletions = append(result.ServiceDeletions, ServiceDiff{
+		Node:      nodeName,
+		ServiceID: serviceID,
+		Expected:  serviceData,
+		Current:   currentService,
+		Line:      line,
+	})
+}
+
+// formatLines formats line numbers as a comma-separated list
+func formatLines(lines []int) string {
+	strs := make([]string, len(lines))
+	for i, line := range lines {
+		strs[i] = fmt.Sprint(line)
+	}
+	return strings.Join(strs, ", ")
+}
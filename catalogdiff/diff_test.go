@@ -0,0 +1,832 @@
+package catalogdiff
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDetectFormat(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected FormatType
+	}{
+		{
+			name: "NDJSON Transaction format",
+			input: `{"Node":{"Verb":"set","Node":{"Node":"web-001","Address":"10.0.0.1"}}}
+{"Service":{"Verb":"set","Node":"web-001","Service":{"ID":"nginx","Port":80}}}`,
+			expected: NDJSONTransactionFormat,
+		},
+		{
+			name:     "Single-line NDJSON Transaction format",
+			input:    `{"Node":{"Verb":"set","Node":{"Node":"web-001","Address":"10.0.0.1"}}}`,
+			expected: NDJSONTransactionFormat,
+		},
+		{
+			name:     "Single-line JSON Catalog Node format",
+			input:    `{"Node":"web-001","Address":"10.0.0.1","Meta":{"role":"web"}}`,
+			expected: JSONCatalogNodeFormat,
+		},
+		{
+			name:     "Single-line JSON Catalog Node array",
+			input:    `[{"Node":"web-001","Address":"10.0.0.1"}]`,
+			expected: JSONCatalogNodeFormat,
+		},
+		{
+			name: "JSON Transaction Array format",
+			input: `[
+				{"Node":{"Verb":"set","Node":{"Node":"web-001","Address":"10.0.0.1"}}},
+				{"Service":{"Verb":"set","Node":"web-001","Service":{"ID":"nginx","Port":80}}}
+			]`,
+			expected: JSONTransactionArrayFormat,
+		},
+		{
+			name:     "Empty input",
+			input:    "",
+			expected: UnknownFormat,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := detectFormat([]byte(tt.input))
+			if result != tt.expected {
+				t.Errorf("detectFormat() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestCompareNodeFields(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected map[string]interface{}
+		current  ConsulNode
+		wantDiff int
+	}{
+		{
+			name: "No differences",
+			expected: map[string]interface{}{
+				"Address":    "10.0.0.1",
+				"Datacenter": "dc1",
+			},
+			current: ConsulNode{
+				Address:    "10.0.0.1",
+				Datacenter: "dc1",
+			},
+			wantDiff: 0,
+		},
+		{
+			name: "Address different",
+			expected: map[string]interface{}{
+				"Address": "10.0.0.2",
+			},
+			current: ConsulNode{
+				Address: "10.0.0.1",
+			},
+			wantDiff: 1,
+		},
+		{
+			name: "Meta differences",
+			expected: map[string]interface{}{
+				"Meta": map[string]interface{}{
+					"type":     "web",
+					"location": "rack-1",
+				},
+			},
+			current: ConsulNode{
+				Meta: map[string]string{
+					"type":     "web",
+					"location": "rack-2",
+				},
+			},
+			wantDiff: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diffs := compareNodeFields(tt.expected, tt.current, DiffOptions{})
+			if len(diffs) != tt.wantDiff {
+				t.Errorf("compareNodeFields() returned %d diffs, want %d", len(diffs), tt.wantDiff)
+			}
+		})
+	}
+}
+
+func TestStringSlicesEqual(t *testing.T) {
+	tests := []struct {
+		name string
+		a    []string
+		b    []string
+		want bool
+	}{
+		{
+			name: "Equal slices same order",
+			a:    []string{"tag1", "tag2"},
+			b:    []string{"tag1", "tag2"},
+			want: true,
+		},
+		{
+			name: "Equal slices different order",
+			a:    []string{"tag2", "tag1"},
+			b:    []string{"tag1", "tag2"},
+			want: true,
+		},
+		{
+			name: "Different slices",
+			a:    []string{"tag1", "tag2"},
+			b:    []string{"tag1", "tag3"},
+			want: false,
+		},
+		{
+			name: "Different lengths",
+			a:    []string{"tag1"},
+			b:    []string{"tag1", "tag2"},
+			want: false,
+		},
+		{
+			name: "Empty slices",
+			a:    []string{},
+			b:    []string{},
+			want: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := stringSlicesEqual(tt.a, tt.b); got != tt.want {
+				t.Errorf("stringSlicesEqual() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiffResultHasChanges(t *testing.T) {
+	tests := []struct {
+		name string
+		diff DiffResult
+		want bool
+	}{
+		{
+			name: "No changes",
+			diff: DiffResult{},
+			want: false,
+		},
+		{
+			name: "Node addition",
+			diff: DiffResult{
+				NodeAdditions: []NodeDiff{{Node: "test"}},
+			},
+			want: true,
+		},
+		{
+			name: "Service modification",
+			diff: DiffResult{
+				ServiceModifications: []ServiceDiff{{Node: "test", ServiceID: "web"}},
+			},
+			want: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.diff.HasChanges(); got != tt.want {
+				t.Errorf("HasChanges() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseNDJSON(t *testing.T) {
+	input := `{"Node":{"Verb":"set","Node":{"Node":"web-001","Address":"10.0.0.1"}}}
+{"Service":{"Verb":"set","Node":"web-001","Service":{"ID":"nginx","Port":80}}}`
+
+	ops, err := parseNDJSON([]byte(input))
+	if err != nil {
+		t.Fatalf("parseNDJSON() error = %v", err)
+	}
+
+	if len(ops) != 2 {
+		t.Errorf("parseNDJSON() returned %d operations, want 2", len(ops))
+	}
+
+	// Check first operation is Node
+	if ops[0].Node == nil {
+		t.Error("First operation should be a Node operation")
+	}
+
+	// Check second operation is Service
+	if ops[1].Service == nil {
+		t.Error("Second operation should be a Service operation")
+	}
+}
+
+func TestParseUnknownFields(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		parse       func([]byte) ([]Operation, error)
+		wantLines   []int
+		wantUnknown [][]string
+	}{
+		{
+			name: "NDJSON with unknown operation type and field",
+			input: `{"Node":{"Verb":"set","Node":{"Node":"web-001"}}}
+{"Session":{"Verb":"create"}}
+
+{"Service":{"Verb":"set","Node":"web-001","Prot":80,"Service":{"ID":"nginx"}}}`,
+			parse:       parseNDJSON,
+			wantLines:   []int{1, 2, 4},
+			wantUnknown: [][]string{nil, {"Session"}, {"Service.Prot"}},
+		},
+		{
+			name:        "Unknown fields are sorted",
+			input:       `{"Service":{"Verb":"set","Timeout":5,"Node":"web-001","Prot":80,"Service":{"ID":"nginx"}}}`,
+			parse:       parseNDJSON,
+			wantLines:   []int{1},
+			wantUnknown: [][]string{{"Service.Prot", "Service.Timeout"}},
+		},
+		{
+			name: "JSON array keeps element line numbers",
+			input: `[
+  {"Node":{"Verb":"set","Node":{"Node":"web-001"}}},
+  {
+    "KV": {"Verb": "set", "Key": "foo"}
+  }
+]`,
+			parse:       parseTransactionArrayJSON,
+			wantLines:   []int{2, 3},
+			wantUnknown: [][]string{nil, {"KV"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ops, err := tt.parse([]byte(tt.input))
+			if err != nil {
+				t.Fatalf("parse error = %v", err)
+			}
+			if len(ops) != len(tt.wantLines) {
+				t.Fatalf("got %d operations, want %d", len(ops), len(tt.wantLines))
+			}
+			for i, op := range ops {
+				if op.Line != tt.wantLines[i] {
+					t.Errorf("operation %d: Line = %d, want %d", i, op.Line, tt.wantLines[i])
+				}
+				if !reflect.DeepEqual(op.Unknown, tt.wantUnknown[i]) {
+					t.Errorf("operation %d: Unknown = %v, want %v", i, op.Unknown, tt.wantUnknown[i])
+				}
+			}
+		})
+	}
+}
+
+func TestValidateOperations(t *testing.T) {
+	input := `{"Node":{"Verb":"set","Node":{"Node":"web-001","Meta":{"consul-version":"1"}}}}
+{"Service":{"Verb":"set","Node":"web-001","Service":{"ID":"nginx","Port":"80","Tags":["web"]}}}
+{"Service":{"Verb":"delete","Node":"web-001","Service":{"ID":"nginx"}}}
+{"Service":{"Node":"web-001","Service":{"ID":"redis","Port":6379}}}
+{"Session":{"Verb":"create"}}`
+
+	ops, err := parseNDJSON([]byte(input))
+	if err != nil {
+		t.Fatalf("parseNDJSON() error = %v", err)
+	}
+
+	violations := ValidateOperations(ops)
+
+	want := []Violation{
+		{Line: 1, Target: "node web-001", Message: `Node.Meta key "consul-version" uses the reserved "consul-" prefix`},
+		{Line: 2, Target: "service web-001/nginx", Message: "Service.Port must be an integer, got 80"},
+		{Line: 3, Target: "service web-001/nginx", Message: `conflicting verbs "set" and "delete" on the same target (first at line 2)`},
+		{Line: 4, Target: "service web-001/redis", Message: "Verb is required"},
+		{Line: 5, Target: "Session", Message: `unknown field "Session"`},
+	}
+	if !reflect.DeepEqual(violations, want) {
+		t.Errorf("ValidateOperations() =\n%v\nwant\n%v", violations, want)
+	}
+}
+
+func TestResolveOperations(t *testing.T) {
+	input := `{"Node":{"Verb":"set","Node":{"Node":"web-001","Address":"10.0.0.1"}}}
+{"Service":{"Verb":"set","Node":"web-001","Service":{"ID":"nginx","Port":80}}}
+{"Node":{"Verb":"set","Node":{"Node":"web-001","Address":"10.0.0.1"}}}
+{"Service":{"Verb":"delete","Node":"web-001","Service":{"ID":"nginx"}}}
+{"Service":{"Verb":"set","Node":"web-001","Service":{"ID":"redis","Port":6379}}}`
+
+	ops, err := parseNDJSON([]byte(input))
+	if err != nil {
+		t.Fatalf("parseNDJSON() error = %v", err)
+	}
+
+	effective, conflicts := ResolveOperations(ops)
+
+	wantLines := []int{3, 4, 5}
+	if len(effective) != len(wantLines) {
+		t.Fatalf("ResolveOperations() returned %d effective operations, want %d", len(effective), len(wantLines))
+	}
+	for i, op := range effective {
+		if op.Line != wantLines[i] {
+			t.Errorf("effective operation %d: Line = %d, want %d", i, op.Line, wantLines[i])
+		}
+	}
+
+	if len(conflicts) != 2 {
+		t.Fatalf("ResolveOperations() returned %d conflicts, want 2", len(conflicts))
+	}
+	if conflicts[0].Target != "web-001" || conflicts[0].Conflicting {
+		t.Errorf("first conflict = %+v, want non-conflicting duplicate on web-001", conflicts[0])
+	}
+	if conflicts[1].Target != "web-001/nginx" || !conflicts[1].Conflicting {
+		t.Errorf("second conflict = %+v, want conflicting operations on web-001/nginx", conflicts[1])
+	}
+}
+
+func TestResolveOperationsReadOnlyVerbs(t *testing.T) {
+	ops := []Operation{
+		{Node: &NodeOperation{Verb: "set", Node: map[string]interface{}{"Node": "web-001", "Address": "10.0.0.9"}}, Line: 1},
+		{Node: &NodeOperation{Verb: "get", Node: map[string]interface{}{"Node": "web-001"}}, Line: 2},
+		{Service: &ServiceOperation{Verb: "set", Node: "web-001", Service: map[string]interface{}{"ID": "nginx", "Port": float64(8080)}}, Line: 3},
+		{Service: &ServiceOperation{Verb: "get", Node: "web-001", Service: map[string]interface{}{"ID": "nginx"}}, Line: 4},
+	}
+
+	effective, conflicts := ResolveOperations(ops)
+	if len(effective) != len(ops) {
+		t.Errorf("ResolveOperations() returned %d effective operations, want %d", len(effective), len(ops))
+	}
+	if len(conflicts) != 0 {
+		t.Errorf("ResolveOperations() conflicts = %+v, want none", conflicts)
+	}
+
+	state := &ConsulState{
+		Nodes:    map[string]ConsulNode{"web-001": {Node: "web-001", Address: "10.0.0.1"}},
+		Services: map[string][]ConsulService{"web-001": {{ID: "nginx", Service: "nginx", Port: 80}}},
+	}
+	diff, err := Calculate(context.Background(), ops, state, DiffOptions{})
+	if err != nil {
+		t.Fatalf("Calculate() error = %v", err)
+	}
+	if len(diff.NodeModifications) != 1 || len(diff.ServiceModifications) != 1 {
+		t.Errorf("got %d node and %d service modifications, want 1 each", len(diff.NodeModifications), len(diff.ServiceModifications))
+	}
+}
+
+func TestCalculateDiffCascadesNodeDeletion(t *testing.T) {
+	for _, verb := range []string{"delete", "delete-cas"} {
+		t.Run(verb, func(t *testing.T) {
+			ops := []Operation{
+				{Node: &NodeOperation{Verb: verb, Node: map[string]interface{}{"Node": "web-001"}}},
+				{Service: &ServiceOperation{Verb: verb, Node: "web-001", Service: map[string]interface{}{"ID": "redis"}}},
+			}
+			state := &ConsulState{
+				Nodes: map[string]ConsulNode{
+					"web-001": {Node: "web-001", Address: "10.0.0.1"},
+				},
+				Services: map[string][]ConsulService{
+					"web-001": {{ID: "nginx", Service: "nginx"}, {ID: "redis", Service: "redis"}},
+				},
+				Checks: map[string][]ConsulCheck{
+					"web-001": {{Node: "web-001", CheckID: "serfHealth"}},
+				},
+			}
+
+			diff, err := Calculate(context.Background(), ops, state, DiffOptions{})
+			if err != nil {
+				t.Fatalf("Calculate() error = %v", err)
+			}
+
+			if len(diff.NodeDeletions) != 1 {
+				t.Fatalf("got %d node deletions, want 1", len(diff.NodeDeletions))
+			}
+			if len(diff.ServiceDeletions) != 2 {
+				t.Fatalf("got %d service deletions, want 2", len(diff.ServiceDeletions))
+			}
+			if diff.ServiceDeletions[0].ServiceID != "redis" || diff.ServiceDeletions[0].Cascaded {
+				t.Errorf("explicit deletion = %+v, want non-cascaded redis", diff.ServiceDeletions[0])
+			}
+			if diff.ServiceDeletions[1].ServiceID != "nginx" || !diff.ServiceDeletions[1].Cascaded {
+				t.Errorf("implied deletion = %+v, want cascaded nginx", diff.ServiceDeletions[1])
+			}
+			if len(diff.CheckDeletions) != 1 || !diff.CheckDeletions[0].Cascaded {
+				t.Errorf("check deletions = %+v, want one cascaded deletion", diff.CheckDeletions)
+			}
+		})
+	}
+}
+
+func TestCalculateDiffCascadeSkipsServicesWithOperations(t *testing.T) {
+	ops := []Operation{
+		{Node: &NodeOperation{Verb: "delete", Node: map[string]interface{}{"Node": "web-001"}}},
+		{Service: &ServiceOperation{Verb: "set", Node: "web-001", Service: map[string]interface{}{"ID": "nginx", "Port": float64(8080)}}},
+	}
+	state := &ConsulState{
+		Nodes: map[string]ConsulNode{
+			"web-001": {Node: "web-001", Address: "10.0.0.1"},
+		},
+		Services: map[string][]ConsulService{
+			"web-001": {{ID: "nginx", Service: "nginx", Port: 80}, {ID: "redis", Service: "redis"}},
+		},
+	}
+
+	diff, err := Calculate(context.Background(), ops, state, DiffOptions{})
+	if err != nil {
+		t.Fatalf("Calculate() error = %v", err)
+	}
+
+	if len(diff.ServiceModifications) != 1 || diff.ServiceModifications[0].ServiceID != "nginx" {
+		t.Errorf("service modifications = %+v, want nginx", diff.ServiceModifications)
+	}
+	if len(diff.ServiceDeletions) != 1 || diff.ServiceDeletions[0].ServiceID != "redis" || !diff.ServiceDeletions[0].Cascaded {
+		t.Errorf("service deletions = %+v, want only cascaded redis", diff.ServiceDeletions)
+	}
+}
+
+func TestCalculateLogger(t *testing.T) {
+	ops := []Operation{
+		{Node: &NodeOperation{Verb: "set", Node: map[string]interface{}{"Node": "web-001"}}, Line: 1},
+		{Node: &NodeOperation{Verb: "delete", Node: map[string]interface{}{"Node": "web-001"}}, Line: 2},
+	}
+
+	// Without a logger, nothing is written to the standard logger
+	var std bytes.Buffer
+	log.SetOutput(&std)
+	defer log.SetOutput(os.Stderr)
+	Calculate(context.Background(), ops, &ConsulState{}, DiffOptions{})
+	if std.Len() != 0 {
+		t.Errorf("Calculate() wrote %q to the standard logger", std.String())
+	}
+
+	var buf bytes.Buffer
+	Calculate(context.Background(), ops, &ConsulState{}, DiffOptions{Logger: log.New(&buf, "", 0)})
+	if !strings.Contains(buf.String(), "[WARN] node web-001:") {
+		t.Errorf("Calculate() logged %q, want a conflict warning", buf.String())
+	}
+}
+
+func TestCalculateCanceled(t *testing.T) {
+	ops := []Operation{
+		{Node: &NodeOperation{Verb: "set", Node: map[string]interface{}{"Node": "web-001"}}, Line: 1},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	diff, err := Calculate(ctx, ops, &ConsulState{}, DiffOptions{})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Calculate() error = %v, want context.Canceled", err)
+	}
+	if diff != nil {
+		t.Errorf("Calculate() = %+v, want nil", diff)
+	}
+}
+
+func TestCompareServiceFieldsConnect(t *testing.T) {
+	expected := map[string]interface{}{
+		"ID":                "web-sidecar-proxy",
+		"Kind":              "connect-proxy",
+		"EnableTagOverride": true,
+		"Weights":           map[string]interface{}{"Passing": float64(10), "Warning": float64(1)},
+		"TaggedAddresses": map[string]interface{}{
+			"lan": map[string]interface{}{"Address": "10.0.0.1", "Port": float64(21000)},
+		},
+		"Proxy": map[string]interface{}{
+			"DestinationServiceName": "web",
+			"Upstreams": []interface{}{
+				map[string]interface{}{"DestinationName": "db", "LocalBindPort": float64(9191)},
+			},
+		},
+	}
+	current := ConsulService{
+		ID:              "web-sidecar-proxy",
+		Kind:            "connect-proxy",
+		Weights:         &ServiceWeights{Passing: 1, Warning: 1},
+		TaggedAddresses: map[string]ServiceAddress{"lan": {Address: "10.0.0.1", Port: 21000}},
+		Proxy: &ServiceProxy{
+			DestinationServiceName: "web",
+			Upstreams: []ProxyUpstream{
+				{DestinationName: "db", LocalBindPort: 9292},
+				{DestinationName: "cache", LocalBindPort: 9393},
+			},
+		},
+	}
+
+	diffs := compareServiceFields(expected, current, DiffOptions{})
+
+	want := map[string]bool{
+		"EnableTagOverride":                true,
+		"Weights.Passing":                  true,
+		"Proxy.Upstreams[0].LocalBindPort": true,
+		"Proxy.Upstreams[1]":               true,
+	}
+	if len(diffs) != len(want) {
+		t.Fatalf("compareServiceFields() returned %d diffs, want %d: %+v", len(diffs), len(want), diffs)
+	}
+	for _, d := range diffs {
+		if !want[d.Field] {
+			t.Errorf("unexpected diff on %s", d.Field)
+		}
+	}
+}
+
+func TestCompareFieldsStrategies(t *testing.T) {
+	expected := map[string]interface{}{
+		"Tags": []interface{}{"primary", "web"},
+		"Meta": map[string]interface{}{"version": float64(2), "owner": "team-a"},
+		"Proxy": map[string]interface{}{
+			"Upstreams": []interface{}{
+				map[string]interface{}{"Config": map[string]interface{}{"connect_timeout_ms": float64(5000)}},
+			},
+		},
+	}
+	current := ConsulService{
+		Tags: []string{"web", "primary"},
+		Meta: map[string]string{"version": "2", "owner": "team-b"},
+		Proxy: &ServiceProxy{
+			Upstreams: []ProxyUpstream{
+				{Config: map[string]interface{}{"connect_timeout_ms": float64(1000)}},
+			},
+		},
+	}
+
+	diffs := compareServiceFields(expected, current, DiffOptions{})
+
+	wantFields := []string{"Meta.owner", "Proxy.Upstreams[0].Config.connect_timeout_ms"}
+	if len(diffs) != len(wantFields) {
+		t.Fatalf("compareServiceFields() returned %d diffs, want %d: %+v", len(diffs), len(wantFields), diffs)
+	}
+	for i, d := range diffs {
+		if d.Field != wantFields[i] {
+			t.Errorf("diff %d: Field = %s, want %s", i, d.Field, wantFields[i])
+		}
+	}
+}
+
+func TestCompareFieldsSkipsUnmodeledKeys(t *testing.T) {
+	expected := map[string]interface{}{
+		"Port":      float64(8080),
+		"Namespace": "default",
+		"Partition": "default",
+		"Locality":  map[string]interface{}{"Region": "us-east-1"},
+		"Proxy":     map[string]interface{}{"Foo": "bar", "Mode": "transparent"},
+		"Meta":      map[string]interface{}{"extra": "1"},
+	}
+	current := ConsulService{Port: 80, Proxy: &ServiceProxy{Mode: "transparent"}}
+
+	diffs := compareServiceFields(expected, current, DiffOptions{})
+
+	wantFields := []string{"Meta.extra", "Port"}
+	if len(diffs) != len(wantFields) {
+		t.Fatalf("compareServiceFields() returned %d diffs, want %d: %+v", len(diffs), len(wantFields), diffs)
+	}
+	for i, d := range diffs {
+		if d.Field != wantFields[i] {
+			t.Errorf("diff %d: Field = %s, want %s", i, d.Field, wantFields[i])
+		}
+	}
+}
+
+func TestFieldRulesFirstMatchWins(t *testing.T) {
+	rules := fieldRules{
+		{"Meta.version", compareExact},
+		{"Meta.*", compareString},
+		{"*.version", compareSet},
+	}
+
+	for i := 0; i < 20; i++ {
+		if got := rules.strategyFor("Meta.version"); got != compareExact {
+			t.Fatalf("strategyFor(Meta.version) = %v, want compareExact", got)
+		}
+		if got := rules.strategyFor("Meta.owner"); got != compareString {
+			t.Fatalf("strategyFor(Meta.owner) = %v, want compareString", got)
+		}
+	}
+}
+
+func TestMatchPath(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{"Tags", "Tags", true},
+		{"Meta.*", "Meta.version", true},
+		{"Meta.*", "Meta", false},
+		{"Proxy.Upstreams[*].Config.*", "Proxy.Upstreams[3].Config.protocol", true},
+		{"Proxy.Upstreams[*].Config.*", "Proxy.Expose.Config.protocol", false},
+	}
+
+	for _, tt := range tests {
+		if got := matchPath(tt.pattern, tt.path); got != tt.want {
+			t.Errorf("matchPath(%q, %q) = %v, want %v", tt.pattern, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestCompareServiceFieldsStrict(t *testing.T) {
+	expected := map[string]interface{}{
+		"ID":   "nginx",
+		"Port": float64(80),
+		"Meta": map[string]interface{}{"version": "1"},
+	}
+	current := ConsulService{
+		ID:          "nginx",
+		Service:     "nginx",
+		Port:        80,
+		Tags:        []string{"injected"},
+		Meta:        map[string]string{"version": "1", "hotfix": "true"},
+		Weights:     &ServiceWeights{Passing: 1, Warning: 1},
+		CreateIndex: 10,
+		ModifyIndex: 12,
+	}
+
+	tests := []struct {
+		name       string
+		opts       DiffOptions
+		wantFields []string
+	}{
+		{
+			name:       "Strict fields disabled",
+			opts:       DiffOptions{},
+			wantFields: nil,
+		},
+		{
+			name:       "All fields strict",
+			opts:       DiffOptions{StrictFields: []string{"all"}},
+			wantFields: []string{"Meta.hotfix", "Tags"},
+		},
+		{
+			name:       "Only Meta strict",
+			opts:       DiffOptions{StrictFields: []string{"Meta"}},
+			wantFields: []string{"Meta.hotfix"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diffs := compareServiceFields(expected, current, tt.opts)
+			if len(diffs) != len(tt.wantFields) {
+				t.Fatalf("compareServiceFields() returned %d diffs, want %d: %+v", len(diffs), len(tt.wantFields), diffs)
+			}
+			for i, d := range diffs {
+				if d.Field != tt.wantFields[i] {
+					t.Errorf("diff %d: Field = %s, want %s", i, d.Field, tt.wantFields[i])
+				}
+			}
+		})
+	}
+}
+
+func TestApplyIgnoreRules(t *testing.T) {
+	var rules []IgnoreRule
+	for _, line := range []string{"node:web-* Meta.last-deploy", "service:*/nginx Tags", "service:*/consul"} {
+		rule, err := ParseIgnoreRule(line)
+		if err != nil {
+			t.Fatalf("ParseIgnoreRule(%q) error = %v", line, err)
+		}
+		rules = append(rules, rule)
+	}
+
+	result := &DiffResult{
+		NodeModifications: []NodeDiff{
+			{Node: "web-001", Fields: []FieldDiff{{Field: "Meta.last-deploy"}}},
+			{Node: "db-001", Fields: []FieldDiff{{Field: "Meta.last-deploy"}}},
+		},
+		ServiceModifications: []ServiceDiff{
+			{Node: "web-001", ServiceID: "nginx", Fields: []FieldDiff{{Field: "Tags"}, {Field: "Port"}}},
+		},
+		ServiceAdditions: []ServiceDiff{
+			{Node: "web-001", ServiceID: "consul"},
+		},
+	}
+
+	applyIgnoreRules(result, rules)
+
+	if len(result.NodeModifications) != 1 || result.NodeModifications[0].Node != "db-001" {
+		t.Errorf("NodeModifications = %+v, want only db-001", result.NodeModifications)
+	}
+	if len(result.ServiceModifications) != 1 || len(result.ServiceModifications[0].Fields) != 1 {
+		t.Errorf("ServiceModifications = %+v, want nginx with only Port", result.ServiceModifications)
+	}
+	if len(result.ServiceAdditions) != 0 {
+		t.Errorf("ServiceAdditions = %+v, want none", result.ServiceAdditions)
+	}
+	if len(result.Ignored) != 3 {
+		t.Errorf("got %d ignored differences, want 3", len(result.Ignored))
+	}
+}
+
+func TestApplyIgnoreRulesCascadedDeletions(t *testing.T) {
+	rule, err := ParseIgnoreRule("node:web-001")
+	if err != nil {
+		t.Fatalf("ParseIgnoreRule() error = %v", err)
+	}
+
+	result := &DiffResult{
+		NodeDeletions: []NodeDiff{{Node: "web-001"}},
+		ServiceDeletions: []ServiceDiff{
+			{Node: "web-001", ServiceID: "nginx", Cascaded: true},
+			{Node: "web-001", ServiceID: "redis"},
+		},
+		CheckDeletions: []CheckDiff{
+			{Node: "web-001", CheckID: "serfHealth", Cascaded: true},
+		},
+	}
+
+	applyIgnoreRules(result, []IgnoreRule{rule})
+
+	if len(result.NodeDeletions) != 0 || len(result.CheckDeletions) != 0 {
+		t.Errorf("NodeDeletions = %+v, CheckDeletions = %+v, want none", result.NodeDeletions, result.CheckDeletions)
+	}
+	if len(result.ServiceDeletions) != 1 || result.ServiceDeletions[0].ServiceID != "redis" {
+		t.Errorf("ServiceDeletions = %+v, want only the explicit redis deletion", result.ServiceDeletions)
+	}
+	if result.TotalChanges() != 1 {
+		t.Errorf("TotalChanges() = %d, want 1", result.TotalChanges())
+	}
+	if len(result.Ignored) != 3 {
+		t.Errorf("got %d ignored differences, want 3", len(result.Ignored))
+	}
+}
+
+func TestParseIgnoreRuleErrors(t *testing.T) {
+	for _, line := range []string{"", "   ", "web-001", "host:web-001", "node:web-[ Meta", "node:web-001 Meta extra"} {
+		if _, err := ParseIgnoreRule(line); err == nil {
+			t.Errorf("ParseIgnoreRule(%q) succeeded, want error", line)
+		}
+	}
+}
+
+func TestSelectOperations(t *testing.T) {
+	input := `{"Node":{"Verb":"set","Node":{"Node":"web-001","Meta":{"env":"prod"}}}}
+{"Node":{"Verb":"set","Node":{"Node":"web-002","Meta":{"env":"staging"}}}}
+{"Service":{"Verb":"set","Node":"web-001","Service":{"ID":"nginx-1","Service":"nginx","Tags":["canary-a"]}}}
+{"Service":{"Verb":"set","Node":"web-001","Service":{"ID":"redis","Tags":["cache"]}}}
+{"Service":{"Verb":"set","Node":"web-002","Service":{"ID":"nginx-2","Service":"nginx","Tags":["canary-b"]}}}
+{"Check":{"Verb":"set","Node":"web-001","Check":{"CheckID":"service:nginx-1","ServiceID":"nginx-1","ServiceName":"nginx","ServiceTags":["canary-a"]}}}
+{"Check":{"Verb":"set","Node":"web-002","Check":{"CheckID":"mem"}}}`
+
+	ops, err := parseNDJSON([]byte(input))
+	if err != nil {
+		t.Fatalf("parseNDJSON() error = %v", err)
+	}
+
+	mustPattern := func(s string) *Pattern {
+		p, err := CompilePattern(s)
+		if err != nil {
+			t.Fatalf("CompilePattern(%q) error = %v", s, err)
+		}
+		return p
+	}
+
+	tests := []struct {
+		name      string
+		sel       Selector
+		wantLines []int
+	}{
+		{
+			name:      "No selector",
+			sel:       Selector{},
+			wantLines: []int{1, 2, 3, 4, 5, 6, 7},
+		},
+		{
+			name:      "Node glob",
+			sel:       Selector{Node: mustPattern("*-001")},
+			wantLines: []int{1, 3, 4, 6},
+		},
+		{
+			name:      "Service name",
+			sel:       Selector{Service: mustPattern("nginx")},
+			wantLines: []int{3, 5, 6},
+		},
+		{
+			name:      "Node-level check",
+			sel:       Selector{Node: mustPattern("web-002"), Service: mustPattern("*")},
+			wantLines: []int{5},
+		},
+		{
+			name:      "Node meta and tag regex",
+			sel:       Selector{NodeMeta: map[string]*Pattern{"env": mustPattern("prod")}, Tag: mustPattern("/^canary-/")},
+			wantLines: []int{3, 6},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selected := SelectOperations(ops, tt.sel)
+			if len(selected) != len(tt.wantLines) {
+				t.Fatalf("SelectOperations() returned %d operations, want %d", len(selected), len(tt.wantLines))
+			}
+			for i, op := range selected {
+				if op.Line != tt.wantLines[i] {
+					t.Errorf("operation %d: Line = %d, want %d", i, op.Line, tt.wantLines[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,22 @@
+// Package catalogdiff detects differences between Consul Transaction API
+// operations and the current state of the Consul catalog.
+//
+// Operations are loaded from NDJSON or JSON array payloads with
+// LoadOperations and compared with the state provided by a StateSource:
+//
+//	ops, err := catalogdiff.LoadOperations("operations.json", catalogdiff.LoadOptions{})
+//	if err != nil {
+//		return err
+//	}
+//	src := catalogdiff.NewConsulSource("http://127.0.0.1:8500")
+//	diff, err := catalogdiff.Diff(ctx, ops, src, catalogdiff.DiffOptions{})
+//	if err != nil {
+//		return err
+//	}
+//	if diff.HasChanges() {
+//		// ...
+//	}
+//
+// Only fields present in the operations are compared. DiffOptions enable
+// strict field checking and ignore rules.
+package catalogdiff
@@ -0,0 +1,93 @@
+package catalogdiff
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// serverManagedFields lists fields Consul sets itself, which are left out of
+// exported operations
+var serverManagedFields = []string{"CreateIndex", "ModifyIndex"}
+
+// Export reads the registered nodes and services matching the selector and
+// returns them as set operations that reproduce the catalog. Node metadata
+// is taken from Consul. As with SelectOperations, node operations are left
+// out when service criteria are set. Checks are not exported.
+func (s *ConsulSource) Export(ctx context.Context, sel Selector) ([]Operation, error) {
+	nodes, err := s.fetchNodes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].Node < nodes[j].Node
+	})
+
+	serviceCriteria := sel.Service != nil || sel.Tag != nil
+
+	var operations []Operation
+	for _, node := range nodes {
+		if !sel.matchesNode(node.Node, node.Meta) {
+			continue
+		}
+
+		if !serviceCriteria {
+			operations = append(operations, Operation{
+				Node: &NodeOperation{Verb: "set", Node: exportObject(node)},
+			})
+		}
+
+		logf(s.Logger, "[INFO] Fetching services for node: %s", node.Node)
+		services, err := s.fetchNodeServices(ctx, node.Node)
+		if err != nil {
+			if isNotFoundError(err) {
+				// Deregistered between the two requests
+				logf(s.Logger, "[INFO] Node %s not found in Consul", node.Node)
+				continue
+			}
+			return nil, fmt.Errorf("failed to fetch services for node %s: %w", node.Node, err)
+		}
+
+		for _, svc := range services {
+			if !sel.matchesService(svc.ID, svc.Service, svc.Tags) {
+				continue
+			}
+			operations = append(operations, Operation{
+				Service: &ServiceOperation{Verb: "set", Node: node.Node, Service: exportObject(svc)},
+			})
+		}
+	}
+
+	logf(s.Logger, "[INFO] Exported %d operations", len(operations))
+	return operations, nil
+}
+
+// exportObject converts a catalog object to an operation payload, dropping
+// server-managed and unset fields
+func exportObject(v interface{}) map[string]interface{} {
+	obj, _ := ToJSONValue(v).(map[string]interface{})
+	for _, field := range serverManagedFields {
+		delete(obj, field)
+	}
+	for field, value := range obj {
+		if IsZeroValue(value) {
+			delete(obj, field)
+		}
+	}
+	return obj
+}
+
+// WriteNDJSON writes operations in the NDJSON Transaction format, one
+// operation per line
+func WriteNDJSON(w io.Writer, operations []Operation) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	for _, op := range operations {
+		if err := enc.Encode(op); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package catalogdiff
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newExportConsul starts a fake Consul with two nodes and their services
+func newExportConsul(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/catalog/nodes", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[
+			{"Node":"web-002","Address":"10.0.0.2","Datacenter":"dc1","Meta":{"role":"web"},"CreateIndex":12,"ModifyIndex":14},
+			{"Node":"db-001","Address":"10.0.1.1","Datacenter":"dc1","Meta":{"role":"db"},"CreateIndex":10,"ModifyIndex":10}
+		]`))
+	})
+	mux.HandleFunc("/v1/catalog/node/web-002", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"Node":{"Node":"web-002"},"Services":{
+			"nginx":{"ID":"nginx","Service":"nginx","Tags":["v1","<edge>"],"Port":80,"Weights":{"Passing":1,"Warning":1},"CreateIndex":13,"ModifyIndex":13},
+			"app":{"ID":"app","Service":"app","Port":8080,"Meta":{"version":"1.2"},"CreateIndex":14,"ModifyIndex":14}
+		}}`))
+	})
+	mux.HandleFunc("/v1/catalog/node/db-001", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"Node":{"Node":"db-001"},"Services":{"postgres":{"ID":"postgres","Service":"postgres","Port":5432}}}`))
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestExport(t *testing.T) {
+	server := newExportConsul(t)
+
+	tests := []struct {
+		name     string
+		selector func(*Selector) error
+		want     string
+	}{
+		{
+			name: "all nodes",
+			want: `{"Node":{"Verb":"set","Node":{"Address":"10.0.1.1","Datacenter":"dc1","Meta":{"role":"db"},"Node":"db-001"}}}
+{"Service":{"Verb":"set","Node":"db-001","Service":{"ID":"postgres","Port":5432,"Service":"postgres"}}}
+{"Node":{"Verb":"set","Node":{"Address":"10.0.0.2","Datacenter":"dc1","Meta":{"role":"web"},"Node":"web-002"}}}
+{"Service":{"Verb":"set","Node":"web-002","Service":{"ID":"app","Meta":{"version":"1.2"},"Port":8080,"Service":"app"}}}
+{"Service":{"Verb":"set","Node":"web-002","Service":{"ID":"nginx","Port":80,"Service":"nginx","Tags":["v1","<edge>"],"Weights":{"Passing":1,"Warning":1}}}}
+`,
+		},
+		{
+			name:     "node meta",
+			selector: func(s *Selector) error { return s.AddNodeMeta("role=db") },
+			want: `{"Node":{"Verb":"set","Node":{"Address":"10.0.1.1","Datacenter":"dc1","Meta":{"role":"db"},"Node":"db-001"}}}
+{"Service":{"Verb":"set","Node":"db-001","Service":{"ID":"postgres","Port":5432,"Service":"postgres"}}}
+`,
+		},
+		{
+			name: "service",
+			selector: func(s *Selector) error {
+				p, err := CompilePattern("nginx")
+				s.Service = p
+				return err
+			},
+			want: `{"Service":{"Verb":"set","Node":"web-002","Service":{"ID":"nginx","Port":80,"Service":"nginx","Tags":["v1","<edge>"],"Weights":{"Passing":1,"Warning":1}}}}
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sel Selector
+			if tt.selector != nil {
+				if err := tt.selector(&sel); err != nil {
+					t.Fatalf("selector error = %v", err)
+				}
+			}
+
+			operations, err := NewConsulSource(server.URL).Export(context.Background(), sel)
+			if err != nil {
+				t.Fatalf("Export() error = %v", err)
+			}
+
+			var buf bytes.Buffer
+			if err := WriteNDJSON(&buf, operations); err != nil {
+				t.Fatalf("WriteNDJSON() error = %v", err)
+			}
+			if got := buf.String(); got != tt.want {
+				t.Errorf("WriteNDJSON() =\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExportRoundTrip(t *testing.T) {
+	server := newExportConsul(t)
+	src := NewConsulSource(server.URL)
+
+	operations, err := src.Export(context.Background(), Selector{})
+	if err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteNDJSON(&buf, operations); err != nil {
+		t.Fatalf("WriteNDJSON() error = %v", err)
+	}
+	if strings.Contains(buf.String(), "Index") {
+		t.Errorf("export contains server-managed fields:\n%s", buf.String())
+	}
+
+	path := filepath.Join(t.TempDir(), "operations.ndjson")
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadOperations(path, LoadOptions{Strict: true})
+	if err != nil {
+		t.Fatalf("LoadOperations() error = %v", err)
+	}
+	if len(loaded) != len(operations) {
+		t.Fatalf("LoadOperations() returned %d operations, want %d", len(loaded), len(operations))
+	}
+
+	// Strict fields also report values only set in Consul
+	diff, err := Diff(context.Background(), loaded, src, DiffOptions{StrictFields: []string{"all"}})
+	if err != nil {
+		t.Fatalf("Diff() error = %v", err)
+	}
+	if diff.HasChanges() {
+		t.Errorf("Diff() found %d changes after round trip, want none: %+v", diff.TotalChanges(), diff)
+	}
+}
@@ -0,0 +1,235 @@
+package catalogdiff
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+)
+
+// IgnoreRule represents a single rule from an ignore file
+type IgnoreRule struct {
+	Kind   string // "node", "service" or "check"
+	Target string // Glob matched against the node name or "node/id"
+	Field  string // Field path; empty to ignore the whole target
+	Line   int
+}
+
+// String returns the rule as written in the ignore file
+func (r IgnoreRule) String() string {
+	if r.Field == "" {
+		return fmt.Sprintf("%s:%s", r.Kind, r.Target)
+	}
+	return fmt.Sprintf("%s:%s %s", r.Kind, r.Target, r.Field)
+}
+
+// LoadIgnoreRules loads rules from an ignore file. Each non-empty line not
+// starting with "#" has the form "KIND:TARGET [FIELD]", for example
+// "node:web-* Meta.last-deploy" or "service:*/consul".
+func LoadIgnoreRules(filename string) ([]IgnoreRule, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open ignore file: %w", err)
+	}
+	defer file.Close()
+
+	var rules []IgnoreRule
+	scanner := bufio.NewScanner(file)
+
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		rule, err := ParseIgnoreRule(line)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", filename, lineNum, err)
+		}
+		rule.Line = lineNum
+		rules = append(rules, rule)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read ignore file: %w", err)
+	}
+
+	return rules, nil
+}
+
+// ParseIgnoreRule parses a single ignore rule
+func ParseIgnoreRule(line string) (IgnoreRule, error) {
+	var rule IgnoreRule
+
+	parts := strings.Fields(line)
+	if len(parts) == 0 || len(parts) > 2 {
+		return rule, fmt.Errorf("expected \"KIND:TARGET [FIELD]\", got %q", line)
+	}
+
+	kind, target, ok := strings.Cut(parts[0], ":")
+	if !ok || target == "" {
+		return rule, fmt.Errorf("expected \"KIND:TARGET [FIELD]\", got %q", line)
+	}
+
+	switch kind {
+	case "node", "service", "check":
+	default:
+		return rule, fmt.Errorf("unknown kind %q (expected node, service or check)", kind)
+	}
+
+	if _, err := path.Match(target, ""); err != nil {
+		return rule, fmt.Errorf("invalid target pattern %q: %w", target, err)
+	}
+
+	rule.Kind = kind
+	rule.Target = target
+	if len(parts) == 2 {
+		rule.Field = parts[1]
+	}
+
+	return rule, nil
+}
+
+// matchesTarget checks if the rule applies to a target
+func (r IgnoreRule) matchesTarget(kind, target string) bool {
+	if r.Kind != kind {
+		return false
+	}
+	matched, _ := path.Match(r.Target, target)
+	return matched
+}
+
+// matchesField checks if the rule applies to a field path. A rule field
+// matches the field itself and everything nested below it.
+func (r IgnoreRule) matchesField(field string) bool {
+	return field == r.Field ||
+		strings.HasPrefix(field, r.Field+".") ||
+		strings.HasPrefix(field, r.Field+"[") ||
+		matchPath(r.Field, field)
+}
+
+// ignoredTarget returns the rule ignoring a whole target, if any
+func ignoredTarget(rules []IgnoreRule, kind, target string) (IgnoreRule, bool) {
+	for _, rule := range rules {
+		if rule.Field == "" && rule.matchesTarget(kind, target) {
+			return rule, true
+		}
+	}
+	return IgnoreRule{}, false
+}
+
+// ignoredDeletion returns the rule ignoring a target, or for deletions
+// cascaded from a node deletion, the rule ignoring that node
+func ignoredDeletion(rules []IgnoreRule, kind, target, node string, cascaded bool) (IgnoreRule, bool) {
+	if rule, ok := ignoredTarget(rules, kind, target); ok {
+		return rule, true
+	}
+	if cascaded {
+		return ignoredTarget(rules, "node", node)
+	}
+	return IgnoreRule{}, false
+}
+
+// ignoredField returns the rule ignoring a field of a target, if any
+func ignoredField(rules []IgnoreRule, kind, target, field string) (IgnoreRule, bool) {
+	for _, rule := range rules {
+		if rule.Field != "" && rule.matchesTarget(kind, target) && rule.matchesField(field) {
+			return rule, true
+		}
+	}
+	return IgnoreRule{}, false
+}
+
+// applyIgnoreRules moves differences matched by ignore rules from result
+// into result.Ignored, so they are reported but not counted as changes
+func applyIgnoreRules(result *DiffResult, rules []IgnoreRule) {
+	if len(rules) == 0 {
+		return
+	}
+
+	result.NodeAdditions = filterNodeDiffs(result, result.NodeAdditions, "addition", rules)
+	result.NodeModifications = filterNodeDiffs(result, result.NodeModifications, "modification", rules)
+	result.NodeDeletions = filterNodeDiffs(result, result.NodeDeletions, "deletion", rules)
+	result.ServiceAdditions = filterServiceDiffs(result, result.ServiceAdditions, "addition", rules)
+	result.ServiceModifications = filterServiceDiffs(result, result.ServiceModifications, "modification", rules)
+	result.ServiceDeletions = filterServiceDiffs(result, result.ServiceDeletions, "deletion", rules)
+
+	var checks []CheckDiff
+	for _, del := range result.CheckDeletions {
+		target := fmt.Sprintf("%s/%s", del.Node, del.CheckID)
+		if rule, ok := ignoredDeletion(rules, "check", target, del.Node, del.Cascaded); ok {
+			result.recordIgnored("check", target, "deletion", "", rule)
+			continue
+		}
+		checks = append(checks, del)
+	}
+	result.CheckDeletions = checks
+}
+
+// filterNodeDiffs removes ignored node differences and fields
+func filterNodeDiffs(result *DiffResult, diffs []NodeDiff, change string, rules []IgnoreRule) []NodeDiff {
+	var kept []NodeDiff
+	for _, d := range diffs {
+		if rule, ok := ignoredTarget(rules, "node", d.Node); ok {
+			result.recordIgnored("node", d.Node, change, "", rule)
+			continue
+		}
+
+		if change == "modification" {
+			d.Fields = filterFields(result, d.Fields, "node", d.Node, rules)
+			if len(d.Fields) == 0 {
+				continue
+			}
+		}
+		kept = append(kept, d)
+	}
+	return kept
+}
+
+// filterServiceDiffs removes ignored service differences and fields
+func filterServiceDiffs(result *DiffResult, diffs []ServiceDiff, change string, rules []IgnoreRule) []ServiceDiff {
+	var kept []ServiceDiff
+	for _, d := range diffs {
+		target := fmt.Sprintf("%s/%s", d.Node, d.ServiceID)
+		if rule, ok := ignoredDeletion(rules, "service", target, d.Node, d.Cascaded); ok {
+			result.recordIgnored("service", target, change, "", rule)
+			continue
+		}
+
+		if change == "modification" {
+			d.Fields = filterFields(result, d.Fields, "service", target, rules)
+			if len(d.Fields) == 0 {
+				continue
+			}
+		}
+		kept = append(kept, d)
+	}
+	return kept
+}
+
+// filterFields removes ignored field differences
+func filterFields(result *DiffResult, fields []FieldDiff, kind, target string, rules []IgnoreRule) []FieldDiff {
+	var kept []FieldDiff
+	for _, f := range fields {
+		if rule, ok := ignoredField(rules, kind, target, f.Field); ok {
+			result.recordIgnored(kind, target, "modification", f.Field, rule)
+			continue
+		}
+		kept = append(kept, f)
+	}
+	return kept
+}
+
+// recordIgnored records a difference suppressed by an ignore rule
+func (d *DiffResult) recordIgnored(kind, target, change, field string, rule IgnoreRule) {
+	d.Ignored = append(d.Ignored, IgnoredDiff{
+		Kind:   kind,
+		Target: target,
+		Change: change,
+		Field:  field,
+		Rule:   rule,
+	})
+}
@@ -0,0 +1,348 @@
+package catalogdiff
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strings"
+)
+
+// LoadOptions controls how operations are loaded
+type LoadOptions struct {
+	// Strict returns unrecognized keys as an error instead of warnings
+	Strict bool
+
+	// Logger receives the detected format and warnings; nil discards them
+	Logger *log.Logger
+}
+
+// LoadOperations loads operations from a file. Unrecognized keys are logged
+// as warnings, or returned as an error when opts.Strict is set.
+func LoadOperations(filename string, opts LoadOptions) ([]Operation, error) {
+	data, err := readFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	format := detectFormat(data)
+	logf(opts.Logger, "[INFO] Detected format: %s", formatString(format))
+
+	operations, err := parseOperations(data, format)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkUnknownFields(filename, operations, opts); err != nil {
+		return nil, err
+	}
+
+	return operations, nil
+}
+
+// ReadOperations detects the file format and parses its operations,
+// keeping unrecognized keys in Operation.Unknown
+func ReadOperations(filename string) ([]Operation, error) {
+	data, err := readFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return parseOperations(data, detectFormat(data))
+}
+
+// readFile reads the content of an operations file
+func readFile(filename string) ([]byte, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	return data, nil
+}
+
+// parseOperations parses data in the given format
+func parseOperations(data []byte, format FormatType) ([]Operation, error) {
+	switch format {
+	case NDJSONTransactionFormat:
+		return parseNDJSON(data)
+	case JSONTransactionArrayFormat:
+		return parseTransactionArrayJSON(data)
+	case JSONCatalogNodeFormat, JSONCatalogServiceFormat:
+		return nil, fmt.Errorf("catalog format not yet supported for diff operations")
+	default:
+		return nil, fmt.Errorf("unable to detect file format")
+	}
+}
+
+// checkUnknownFields reports unrecognized keys found while parsing
+func checkUnknownFields(filename string, operations []Operation, opts LoadOptions) error {
+	var problems []string
+	for _, op := range operations {
+		for _, field := range op.Unknown {
+			problems = append(problems, fmt.Sprintf("%s:%d: unknown field %q", filename, op.Line, field))
+		}
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+
+	if opts.Strict {
+		return fmt.Errorf("unrecognized fields in input:\n  %s", strings.Join(problems, "\n  "))
+	}
+
+	for _, problem := range problems {
+		logf(opts.Logger, "[WARN] %s (ignored)", problem)
+	}
+	return nil
+}
+
+// detectFormat detects the format of the input data
+func detectFormat(data []byte) FormatType {
+	// Try to detect NDJSON first
+	if format := detectNDJSONFormat(data); format != UnknownFormat {
+		return format
+	}
+
+	// Try to parse as single JSON
+	return detectSingleJSONFormat(data)
+}
+
+// detectNDJSONFormat checks if data is in NDJSON format
+func detectNDJSONFormat(data []byte) FormatType {
+	lines := bytes.Split(bytes.TrimSpace(data), []byte("\n"))
+	for _, line := range lines {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+
+		var obj map[string]interface{}
+		if err := json.Unmarshal(line, &obj); err != nil {
+			return UnknownFormat
+		}
+
+		if containsVerb(obj) {
+			return NDJSONTransactionFormat
+		}
+	}
+
+	return UnknownFormat
+}
+
+// detectSingleJSONFormat checks single JSON format
+func detectSingleJSONFormat(data []byte) FormatType {
+	var jsonData interface{}
+	if err := json.Unmarshal(data, &jsonData); err != nil {
+		return UnknownFormat
+	}
+
+	switch v := jsonData.(type) {
+	case map[string]interface{}:
+		return detectJSONObjectFormat(v)
+	case []interface{}:
+		return detectJSONArrayFormat(v)
+	default:
+		return UnknownFormat
+	}
+}
+
+// detectJSONObjectFormat detects format for JSON object
+func detectJSONObjectFormat(v map[string]interface{}) FormatType {
+	// Check for node format
+	if _, hasNode := v["Node"]; hasNode {
+		return JSONCatalogNodeFormat
+	}
+
+	return UnknownFormat
+}
+
+// detectJSONArrayFormat detects format for JSON array
+func detectJSONArrayFormat(v []interface{}) FormatType {
+	if len(v) == 0 {
+		return UnknownFormat
+	}
+
+	first, ok := v[0].(map[string]interface{})
+	if !ok {
+		return UnknownFormat
+	}
+
+	// Check if it's an array of operations (Transaction format)
+	if containsVerb(first) {
+		return JSONTransactionArrayFormat
+	}
+
+	// Check for catalog node format
+	if _, hasAddress := first["Address"]; hasAddress {
+		return JSONCatalogNodeFormat
+	}
+
+	// Check for catalog service format
+	if _, hasService := first["Service"]; hasService {
+		return JSONCatalogServiceFormat
+	}
+
+	return UnknownFormat
+}
+
+// parseNDJSON parses NDJSON format
+func parseNDJSON(data []byte) ([]Operation, error) {
+	var operations []Operation
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		op, err := decodeOperation(line, lineNum)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse line %d: %w", lineNum, err)
+		}
+		operations = append(operations, op)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read NDJSON: %w", err)
+	}
+
+	return operations, nil
+}
+
+// parseTransactionArrayJSON parses JSON array of transaction operations
+func parseTransactionArrayJSON(data []byte) ([]Operation, error) {
+	var operations []Operation
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if _, err := dec.Token(); err != nil {
+		return nil, fmt.Errorf("failed to parse transaction array JSON: %w", err)
+	}
+
+	for dec.More() {
+		lineNum := lineAt(data, dec.InputOffset())
+
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return nil, fmt.Errorf("failed to parse transaction array JSON: %w", err)
+		}
+
+		op, err := decodeOperation(raw, lineNum)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse operation at line %d: %w", lineNum, err)
+		}
+		operations = append(operations, op)
+	}
+
+	return operations, nil
+}
+
+// lineAt returns the line number of the first value at or after offset
+func lineAt(data []byte, offset int64) int {
+	i := int(offset)
+	for i < len(data) && strings.ContainsRune(" \t\r\n,", rune(data[i])) {
+		i++
+	}
+	return 1 + bytes.Count(data[:i], []byte("\n"))
+}
+
+// knownOperationFields lists the keys accepted inside each operation type
+var knownOperationFields = map[string][]string{
+	"Node":    {"Verb", "Node"},
+	"Service": {"Verb", "Node", "Service"},
+	"Check":   {"Verb", "Node", "Check"},
+}
+
+// decodeOperation decodes a single operation and records unrecognized keys
+func decodeOperation(raw []byte, lineNum int) (Operation, error) {
+	var op Operation
+	if err := json.Unmarshal(raw, &op); err != nil {
+		return op, err
+	}
+	op.Line = lineNum
+
+	var top map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &top); err != nil {
+		return op, err
+	}
+
+	for key, value := range top {
+		fields, known := lookupField(knownOperationFields, key)
+		if !known {
+			op.Unknown = append(op.Unknown, key)
+			continue
+		}
+
+		var inner map[string]json.RawMessage
+		if err := json.Unmarshal(value, &inner); err != nil {
+			continue // Type errors are reported by the Operation decode
+		}
+		for innerKey := range inner {
+			if !containsFold(fields, innerKey) {
+				op.Unknown = append(op.Unknown, key+"."+innerKey)
+			}
+		}
+	}
+	sort.Strings(op.Unknown)
+
+	return op, nil
+}
+
+// lookupField finds a key case-insensitively, as encoding/json does
+func lookupField(fields map[string][]string, key string) ([]string, bool) {
+	for name, inner := range fields {
+		if strings.EqualFold(name, key) {
+			return inner, true
+		}
+	}
+	return nil, false
+}
+
+// containsFold checks if list contains s, ignoring case
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// containsVerb checks if the object contains a Verb field
+func containsVerb(obj map[string]interface{}) bool {
+	for _, v := range obj {
+		if nested, ok := v.(map[string]interface{}); ok {
+			if _, hasVerb := nested["Verb"]; hasVerb {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// formatString returns a human-readable format name
+func formatString(format FormatType) string {
+	switch format {
+	case NDJSONTransactionFormat:
+		return "NDJSON Transaction format"
+	case JSONTransactionArrayFormat:
+		return "JSON Transaction Array format"
+	case JSONCatalogNodeFormat:
+		return "JSON Catalog Node format"
+	case JSONCatalogServiceFormat:
+		return "JSON Catalog Service format"
+	default:
+		return "Unknown format"
+	}
+}
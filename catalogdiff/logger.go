@@ -0,0 +1,11 @@
+package catalogdiff
+
+import "log"
+
+// logf writes a message to logger. A nil logger discards it, so the package
+// stays silent unless the caller passes a logger.
+func logf(logger *log.Logger, format string, v ...interface{}) {
+	if logger != nil {
+		logger.Printf(format, v...)
+	}
+}
@@ -0,0 +1,191 @@
+package catalogdiff
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+// msgpackDecoder decodes MessagePack values into generic Go values: maps
+// become map[string]interface{}, arrays []interface{}, integers int64 (or
+// uint64 past the int64 range) and both str and bin become strings.
+// Extension values, such as encoded timestamps, decode to nil.
+type msgpackDecoder struct {
+	r *bufio.Reader
+}
+
+// newMsgpackDecoder creates a decoder reading from r
+func newMsgpackDecoder(r io.Reader) *msgpackDecoder {
+	return &msgpackDecoder{r: bufio.NewReader(r)}
+}
+
+// decode decodes the next value, returning io.EOF at the end of the input
+func (d *msgpackDecoder) decode() (interface{}, error) {
+	b, err := d.r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case b <= 0x7f:
+		return int64(b), nil
+	case b >= 0xe0:
+		return int64(int8(b)), nil
+	case b&0xf0 == 0x80:
+		return d.decodeMap(uint64(b & 0x0f))
+	case b&0xf0 == 0x90:
+		return d.decodeArray(uint64(b & 0x0f))
+	case b&0xe0 == 0xa0:
+		return d.readString(uint64(b & 0x1f))
+	}
+
+	switch b {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xc4, 0xd9:
+		return d.withLength(1, d.readString)
+	case 0xc5, 0xda:
+		return d.withLength(2, d.readString)
+	case 0xc6, 0xdb:
+		return d.withLength(4, d.readString)
+	case 0xc7:
+		return d.withLength(1, d.skipExt)
+	case 0xc8:
+		return d.withLength(2, d.skipExt)
+	case 0xc9:
+		return d.withLength(4, d.skipExt)
+	case 0xca:
+		bits, err := d.readUint(4)
+		return float64(math.Float32frombits(uint32(bits))), err
+	case 0xcb:
+		bits, err := d.readUint(8)
+		return math.Float64frombits(bits), err
+	case 0xcc, 0xcd, 0xce:
+		v, err := d.readUint(1 << (b - 0xcc))
+		return int64(v), err
+	case 0xcf:
+		v, err := d.readUint(8)
+		if v > math.MaxInt64 {
+			return v, err
+		}
+		return int64(v), err
+	case 0xd0:
+		v, err := d.readUint(1)
+		return int64(int8(v)), err
+	case 0xd1:
+		v, err := d.readUint(2)
+		return int64(int16(v)), err
+	case 0xd2:
+		v, err := d.readUint(4)
+		return int64(int32(v)), err
+	case 0xd3:
+		v, err := d.readUint(8)
+		return int64(v), err
+	case 0xd4, 0xd5, 0xd6, 0xd7, 0xd8:
+		return d.skipExt(1 << (b - 0xd4))
+	case 0xdc:
+		return d.withLength(2, d.decodeArray)
+	case 0xdd:
+		return d.withLength(4, d.decodeArray)
+	case 0xde:
+		return d.withLength(2, d.decodeMap)
+	case 0xdf:
+		return d.withLength(4, d.decodeMap)
+	}
+
+	return nil, fmt.Errorf("invalid msgpack type byte 0x%02x", b)
+}
+
+// decodeMap decodes a map of n entries
+func (d *msgpackDecoder) decodeMap(n uint64) (interface{}, error) {
+	m := make(map[string]interface{}, min(n, 64))
+	for i := uint64(0); i < n; i++ {
+		key, err := d.decodeValue()
+		if err != nil {
+			return nil, err
+		}
+		value, err := d.decodeValue()
+		if err != nil {
+			return nil, err
+		}
+		m[fmt.Sprint(key)] = value
+	}
+	return m, nil
+}
+
+// decodeArray decodes an array of n items
+func (d *msgpackDecoder) decodeArray(n uint64) (interface{}, error) {
+	items := make([]interface{}, 0, min(n, 64))
+	for i := uint64(0); i < n; i++ {
+		item, err := d.decodeValue()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// decodeValue decodes a value nested in a map or array, where the end of
+// the input is an error
+func (d *msgpackDecoder) decodeValue() (interface{}, error) {
+	v, err := d.decode()
+	if err == io.EOF {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return v, err
+}
+
+// readString reads n bytes as a string
+func (d *msgpackDecoder) readString(n uint64) (interface{}, error) {
+	data, err := d.readBytes(n)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// skipExt skips the type and n data bytes of an extension value
+func (d *msgpackDecoder) skipExt(n uint64) (interface{}, error) {
+	_, err := d.readBytes(n + 1)
+	return nil, err
+}
+
+// readBytes reads exactly n bytes without trusting n for the allocation
+func (d *msgpackDecoder) readBytes(n uint64) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(d.r, int64(n)))
+	if err != nil {
+		return nil, err
+	}
+	if uint64(len(data)) != n {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return data, nil
+}
+
+// readUint reads a big-endian unsigned integer of size bytes
+func (d *msgpackDecoder) readUint(size int) (uint64, error) {
+	var buf [8]byte
+	if _, err := io.ReadFull(d.r, buf[8-size:]); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return 0, err
+	}
+	return binary.BigEndian.Uint64(buf[:]), nil
+}
+
+// withLength reads a length prefix of size bytes and decodes the value
+func (d *msgpackDecoder) withLength(size int, decode func(n uint64) (interface{}, error)) (interface{}, error) {
+	n, err := d.readUint(size)
+	if err != nil {
+		return nil, err
+	}
+	return decode(n)
+}
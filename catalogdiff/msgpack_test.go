@@ -0,0 +1,57 @@
+package catalogdiff
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestMsgpackDecode(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   []byte
+		want    interface{}
+		wantErr error
+	}{
+		{name: "positive fixint", input: []byte{0x2a}, want: int64(42)},
+		{name: "negative fixint", input: []byte{0xff}, want: int64(-1)},
+		{name: "uint16", input: []byte{0xcd, 0x1f, 0x90}, want: int64(8080)},
+		{name: "int32", input: []byte{0xd2, 0xff, 0xff, 0xff, 0xfe}, want: int64(-2)},
+		{name: "uint64 past int64", input: []byte{0xcf, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, want: uint64(1<<64 - 1)},
+		{name: "float64", input: []byte{0xcb, 0x3f, 0xf8, 0, 0, 0, 0, 0, 0}, want: 1.5},
+		{name: "nil", input: []byte{0xc0}, want: nil},
+		{name: "true", input: []byte{0xc3}, want: true},
+		{name: "fixstr", input: []byte{0xa3, 'w', 'e', 'b'}, want: "web"},
+		{name: "str8", input: []byte{0xd9, 0x02, 'd', 'b'}, want: "db"},
+		{name: "bin8", input: []byte{0xc4, 0x01, 'x'}, want: "x"},
+		{name: "fixarray", input: []byte{0x92, 0x01, 0xa1, 'a'}, want: []interface{}{int64(1), "a"}},
+		{
+			name:  "fixmap",
+			input: []byte{0x82, 0xa4, 'N', 'o', 'd', 'e', 0xa3, 'w', 'e', 'b', 0xa4, 'P', 'o', 'r', 't', 0x50},
+			want:  map[string]interface{}{"Node": "web", "Port": int64(80)},
+		},
+		{name: "fixext skipped", input: []byte{0x91, 0xd6, 0xff, 0, 0, 0, 1}, want: []interface{}{nil}},
+		{name: "ext8 skipped", input: []byte{0xc7, 0x02, 0x05, 0, 0}, want: nil},
+		{name: "empty input", input: nil, wantErr: io.EOF},
+		{name: "truncated string", input: []byte{0xa3, 'w'}, wantErr: io.ErrUnexpectedEOF},
+		{name: "truncated map", input: []byte{0x81, 0xa1, 'a'}, wantErr: io.ErrUnexpectedEOF},
+		{name: "truncated length", input: []byte{0xda, 0x01}, wantErr: io.ErrUnexpectedEOF},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := newMsgpackDecoder(bytes.NewReader(tt.input)).decode()
+			if err != tt.wantErr {
+				t.Fatalf("decode() error = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("decode() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := newMsgpackDecoder(bytes.NewReader([]byte{0xc1})).decode(); err == nil {
+		t.Error("decode() of the reserved type byte succeeded, want error")
+	}
+}
@@ -0,0 +1,432 @@
+package catalogdiff
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// extractNodeInfo extracts node information from the operation
+func extractNodeInfo(nodeOp map[string]interface{}) (string, map[string]interface{}) {
+	nodeName := ""
+	if name, ok := nodeOp["Node"].(string); ok {
+		nodeName = name
+	}
+	return nodeName, nodeOp
+}
+
+// extractServiceInfo extracts service information from the operation
+func extractServiceInfo(serviceOp *ServiceOperation) (string, string, map[string]interface{}) {
+	nodeName := serviceOp.Node
+	serviceID := ""
+
+	if svc, ok := serviceOp.Service["ID"].(string); ok {
+		serviceID = svc
+	} else if svc, ok := serviceOp.Service["Service"].(string); ok {
+		serviceID = svc
+	}
+
+	return nodeName, serviceID, serviceOp.Service
+}
+
+// groupOperationsByTarget groups operations by their target (node/service)
+func groupOperationsByTarget(operations []Operation) (map[string]*NodeOperation, map[string]*ServiceOperation) {
+	nodes := make(map[string]*NodeOperation)
+	services := make(map[string]*ServiceOperation)
+
+	for _, op := range operations {
+		if op.Node != nil {
+			nodeName, _ := extractNodeInfo(op.Node.Node)
+			if nodeName != "" {
+				nodes[nodeName] = op.Node
+			}
+		}
+
+		if op.Service != nil {
+			nodeName, serviceID, _ := extractServiceInfo(op.Service)
+			key := fmt.Sprintf("%s/%s", nodeName, serviceID)
+			services[key] = op.Service
+		}
+	}
+
+	return nodes, services
+}
+
+// normalizeValue converts interface{} values to comparable types
+func normalizeValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case float64:
+		// JSON numbers are parsed as float64, but we might want int
+		if val == float64(int(val)) {
+			return int(val)
+		}
+		return val
+	case []interface{}:
+		// Normalize arrays
+		return normalizeArray(val)
+	case map[string]interface{}:
+		// Normalize nested maps
+		return normalizeMap(val)
+	default:
+		return v
+	}
+}
+
+// normalizeArray normalizes an array of values
+func normalizeArray(arr []interface{}) []interface{} {
+	result := make([]interface{}, len(arr))
+	for i, item := range arr {
+		result[i] = normalizeValue(item)
+	}
+	return result
+}
+
+// normalizeMap normalizes a map of values
+func normalizeMap(m map[string]interface{}) map[string]interface{} {
+	result := make(map[string]interface{})
+	for k, v := range m {
+		result[k] = normalizeValue(v)
+	}
+	return result
+}
+
+// compareStrategy defines how an expected value is compared at a path
+type compareStrategy int
+
+const (
+	// compareExact recurses into maps and arrays and compares scalars after normalization
+	compareExact compareStrategy = iota
+	// compareSet compares arrays as unordered sets of strings
+	compareSet
+	// compareString compares values by their string form
+	compareString
+)
+
+// fieldRule applies a comparison strategy to the paths matching a pattern.
+// Patterns are dotted paths where "*" matches any map key and "[*]" any
+// array index.
+type fieldRule struct {
+	pattern  string
+	strategy compareStrategy
+}
+
+// fieldRules lists comparison rules; the first rule matching a path wins
+type fieldRules []fieldRule
+
+// fieldComparator compares payload fields against the current state of an object
+type fieldComparator struct {
+	rules    fieldRules
+	managed  []string               // Top-level fields never reported as unexpected
+	defaults map[string]interface{} // Values Consul assigns to omitted fields
+}
+
+// nodeComparator compares node fields
+var nodeComparator = fieldComparator{
+	rules: fieldRules{
+		{"Meta.*", compareString},
+		{"TaggedAddresses.*", compareString},
+	},
+	managed: []string{"ID", "Node", "Datacenter", "CreateIndex", "ModifyIndex"},
+}
+
+// serviceComparator compares service fields
+var serviceComparator = fieldComparator{
+	rules: fieldRules{
+		{"Tags", compareSet},
+		{"Meta.*", compareString},
+	},
+	managed: []string{"ID", "Service", "CreateIndex", "ModifyIndex"},
+	defaults: map[string]interface{}{
+		"Weights": map[string]interface{}{"Passing": 1, "Warning": 1},
+	},
+}
+
+// compareNodeFields compares node fields and returns differences
+func compareNodeFields(expected map[string]interface{}, current ConsulNode, opts DiffOptions) []FieldDiff {
+	return nodeComparator.compare(expected, current, opts)
+}
+
+// compareServiceFields compares service fields and returns differences
+func compareServiceFields(expected map[string]interface{}, current ConsulService, opts DiffOptions) []FieldDiff {
+	return serviceComparator.compare(expected, current, opts)
+}
+
+// compare walks every field defined in expected and compares it with the
+// JSON form of current. Fields only present in current are ignored unless
+// strict field checking is enabled for them, and fields the type of current
+// does not model, such as Namespace or Partition, are not compared.
+func (c fieldComparator) compare(expected map[string]interface{}, current interface{}, opts DiffOptions) []FieldDiff {
+	return c.compareValue("", expected, ToJSONValue(current), reflect.TypeOf(current), opts)
+}
+
+// ToJSONValue converts a value to its generic JSON representation
+func ToJSONValue(v interface{}) interface{} {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+
+	var result interface{}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil
+	}
+	return result
+}
+
+// compareValue compares an expected value with the current value at path,
+// where typ is the Go type current was encoded from, or nil if unknown
+func (c fieldComparator) compareValue(path string, expected, current interface{}, typ reflect.Type, opts DiffOptions) []FieldDiff {
+	switch c.rules.strategyFor(path) {
+	case compareSet:
+		expectedSet, currentSet := stringSlice(expected), stringSlice(current)
+		if !stringSlicesEqual(expectedSet, currentSet) {
+			return []FieldDiff{{Field: path, Expected: expectedSet, Current: currentSet}}
+		}
+		return nil
+
+	case compareString:
+		expectedStr, currentStr := fmt.Sprint(expected), ""
+		if current != nil {
+			currentStr = fmt.Sprint(current)
+		}
+		if expectedStr != currentStr {
+			return []FieldDiff{{Field: path, Expected: expected, Current: currentStr}}
+		}
+		return nil
+	}
+
+	switch exp := expected.(type) {
+	case map[string]interface{}:
+		cur, _ := current.(map[string]interface{})
+		var diffs []FieldDiff
+		for _, key := range sortedKeys(exp) {
+			keyType, modeled := fieldType(typ, key)
+			if !modeled {
+				continue
+			}
+			diffs = append(diffs, c.compareValue(joinPath(path, key), exp[key], cur[key], keyType, opts)...)
+		}
+		return append(diffs, c.compareUnexpected(path, exp, cur, opts)...)
+
+	case []interface{}:
+		cur, _ := current.([]interface{})
+		var diffs []FieldDiff
+		for i, item := range exp {
+			var currentItem interface{}
+			if i < len(cur) {
+				currentItem = cur[i]
+			}
+			diffs = append(diffs, c.compareValue(fmt.Sprintf("%s[%d]", path, i), item, currentItem, elemType(typ), opts)...)
+		}
+		// Elements present in Consul beyond those defined in the payload
+		for i := len(exp); i < len(cur); i++ {
+			diffs = append(diffs, FieldDiff{
+				Field:    fmt.Sprintf("%s[%d]", path, i),
+				Expected: nil,
+				Current:  normalizeValue(cur[i]),
+			})
+		}
+		return diffs
+
+	default:
+		expectedVal, currentVal := normalizeValue(expected), normalizeValue(current)
+		if !reflect.DeepEqual(expectedVal, currentVal) {
+			return []FieldDiff{{Field: path, Expected: expectedVal, Current: currentVal}}
+		}
+		return nil
+	}
+}
+
+// fieldType returns the type of the value stored under key by a value of
+// type typ, and whether typ models key at all. Struct types model the keys
+// of their JSON fields, maps model every key. A nil typ models every key.
+func fieldType(typ reflect.Type, key string) (reflect.Type, bool) {
+	typ = indirectType(typ)
+	if typ == nil {
+		return nil, true
+	}
+
+	switch typ.Kind() {
+	case reflect.Struct:
+		for i := 0; i < typ.NumField(); i++ {
+			field := typ.Field(i)
+			name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if name == "" {
+				name = field.Name
+			}
+			if name == key && name != "-" {
+				return field.Type, true
+			}
+		}
+		return nil, false
+	case reflect.Map:
+		return typ.Elem(), true
+	}
+	// A scalar where the payload has an object is a difference to report
+	return nil, true
+}
+
+// elemType returns the element type of a slice type, or nil
+func elemType(typ reflect.Type) reflect.Type {
+	typ = indirectType(typ)
+	if typ == nil || (typ.Kind() != reflect.Slice && typ.Kind() != reflect.Array) {
+		return nil
+	}
+	return typ.Elem()
+}
+
+// indirectType dereferences pointer types, returning nil for interfaces
+// whose dynamic type is not known
+func indirectType(typ reflect.Type) reflect.Type {
+	for typ != nil && typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	if typ != nil && typ.Kind() == reflect.Interface {
+		return nil
+	}
+	return typ
+}
+
+// compareUnexpected reports keys present in current but absent from expected
+// when strict field checking is enabled for them, skipping managed fields and
+// values that match what Consul assigns by default
+func (c fieldComparator) compareUnexpected(path string, expected, current map[string]interface{}, opts DiffOptions) []FieldDiff {
+	var diffs []FieldDiff
+
+	for _, key := range sortedKeys(current) {
+		if _, defined := expected[key]; defined {
+			continue
+		}
+
+		field := joinPath(path, key)
+		if !opts.isStrictField(topLevelField(field)) {
+			continue
+		}
+		if path == "" && containsFold(c.managed, key) {
+			continue
+		}
+		if c.isDefault(field, current[key]) {
+			continue
+		}
+
+		diffs = append(diffs, FieldDiff{
+			Field:    field,
+			Expected: nil,
+			Current:  normalizeValue(current[key]),
+		})
+	}
+
+	return diffs
+}
+
+// isDefault checks if a value is the zero value or the default Consul assigns
+func (c fieldComparator) isDefault(path string, v interface{}) bool {
+	if def, ok := c.defaults[path]; ok && reflect.DeepEqual(normalizeValue(def), normalizeValue(v)) {
+		return true
+	}
+	return IsZeroValue(v)
+}
+
+// IsZeroValue checks if a JSON value is empty, recursing into objects
+func IsZeroValue(v interface{}) bool {
+	switch val := v.(type) {
+	case nil:
+		return true
+	case string:
+		return val == ""
+	case float64:
+		return val == 0
+	case bool:
+		return !val
+	case []interface{}:
+		return len(val) == 0
+	case map[string]interface{}:
+		for _, item := range val {
+			if !IsZeroValue(item) {
+				return false
+			}
+		}
+		return true
+	default:
+		return false
+	}
+}
+
+// topLevelField returns the first segment of a dotted path
+func topLevelField(path string) string {
+	if idx := strings.IndexAny(path, ".["); idx >= 0 {
+		return path[:idx]
+	}
+	return path
+}
+
+// strategyFor returns the comparison strategy for a path
+func (r fieldRules) strategyFor(path string) compareStrategy {
+	for _, rule := range r {
+		if matchPath(rule.pattern, path) {
+			return rule.strategy
+		}
+	}
+	return compareExact
+}
+
+// matchPath matches a dotted path against a pattern segment by segment
+func matchPath(pattern, path string) bool {
+	patternSegs := strings.Split(pattern, ".")
+	pathSegs := strings.Split(path, ".")
+	if len(patternSegs) != len(pathSegs) {
+		return false
+	}
+
+	for i, seg := range patternSegs {
+		switch {
+		case seg == "*" || seg == pathSegs[i]:
+			continue
+		case strings.HasSuffix(seg, "[*]"):
+			name := strings.TrimSuffix(seg, "[*]")
+			if !strings.HasPrefix(pathSegs[i], name+"[") || !strings.HasSuffix(pathSegs[i], "]") {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// joinPath appends a key to a dotted path
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// stringSlice converts a JSON array to a slice of strings
+func stringSlice(v interface{}) []string {
+	arr, _ := v.([]interface{})
+	strs := make([]string, len(arr))
+	for i, item := range arr {
+		strs[i] = fmt.Sprint(item)
+	}
+	return strs
+}
+
+// stringSlicesEqual compares two string slices
+func stringSlicesEqual(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	// Create maps for comparison (order doesn't matter for tags)
+	aMap := make(map[string]bool)
+	for _, s := range a {
+		aMap[s] = true
+	}
+	for _, s := range b {
+		if !aMap[s] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,126 @@
+package catalogdiff
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// recordedResponse is a Consul HTTP response saved by RecordingTransport
+type recordedResponse struct {
+	Method     string      `json:"Method"`
+	URL        string      `json:"URL"` // Path and query, without the Consul address
+	StatusCode int         `json:"StatusCode"`
+	Header     http.Header `json:"Header"`
+	Body       string      `json:"Body"`
+}
+
+// RecordingTransport saves every response to a file in Dir, so that a run
+// can be reproduced later with ReplayTransport
+type RecordingTransport struct {
+	Dir  string
+	Next http.RoundTripper // http.DefaultTransport if nil
+}
+
+// RoundTrip sends the request and records the response
+func (t *RecordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	next := t.Next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	resp, err := next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	rec := recordedResponse{
+		Method:     req.Method,
+		URL:        req.URL.RequestURI(),
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+		Body:       string(body),
+	}
+	data, err := json.MarshalIndent(rec, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(t.Dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create record directory: %w", err)
+	}
+	if err := os.WriteFile(recordPath(t.Dir, req), append(data, '\n'), 0644); err != nil {
+		return nil, fmt.Errorf("failed to record response: %w", err)
+	}
+
+	return resp, nil
+}
+
+// ReplayTransport serves responses saved by RecordingTransport from Dir
+// instead of sending requests. Requests that were not recorded fail.
+type ReplayTransport struct {
+	Dir string
+}
+
+// RoundTrip returns the recorded response for the request
+func (t *ReplayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+
+	data, err := os.ReadFile(recordPath(t.Dir, req))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("no recorded response for %s %s in %s", req.Method, req.URL.RequestURI(), t.Dir)
+		}
+		return nil, fmt.Errorf("failed to read recorded response: %w", err)
+	}
+
+	var rec recordedResponse
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return nil, fmt.Errorf("failed to parse recorded response for %s: %w", req.URL.RequestURI(), err)
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", rec.StatusCode, http.StatusText(rec.StatusCode)),
+		StatusCode:    rec.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        rec.Header,
+		Body:          io.NopCloser(bytes.NewReader([]byte(rec.Body))),
+		ContentLength: int64(len(rec.Body)),
+		Request:       req,
+	}, nil
+}
+
+// recordPath returns the file a request is recorded in, named after its
+// path and query so that recordings do not depend on the Consul address.
+// The consistency parameters are left out, so a recording can be replayed
+// in any consistency mode.
+func recordPath(dir string, req *http.Request) string {
+	u := *req.URL
+	var params []string
+	for _, param := range strings.Split(u.RawQuery, "&") {
+		switch param {
+		case "", ConsistencyStale, ConsistencyConsistent:
+			continue
+		}
+		params = append(params, param)
+	}
+	u.RawQuery = strings.Join(params, "&")
+	return filepath.Join(dir, url.PathEscape(strings.TrimPrefix(u.RequestURI(), "/"))+".json")
+}
@@ -0,0 +1,79 @@
+package catalogdiff
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRecordReplay(t *testing.T) {
+	server := newTestConsul(t)
+	dir := t.TempDir()
+
+	operations := []Operation{
+		{Node: &NodeOperation{Verb: "set", Node: map[string]interface{}{"Node": "web-001", "Address": "10.0.0.2"}}},
+		{Service: &ServiceOperation{Verb: "set", Node: "web-001", Service: map[string]interface{}{"ID": "nginx", "Port": float64(80)}}},
+		{Service: &ServiceOperation{Verb: "set", Node: "web-002", Service: map[string]interface{}{"ID": "redis"}}},
+	}
+
+	recording := NewConsulSource(server.URL)
+	recording.Client.Transport = &RecordingTransport{Dir: dir}
+	recorded, err := Diff(context.Background(), operations, recording, DiffOptions{})
+	if err != nil {
+		t.Fatalf("Diff() with recording error = %v", err)
+	}
+
+	// Replay must not need the server, nor the same address
+	server.Close()
+	replaying := &ConsulSource{Addr: "http://consul.invalid:8500", Client: &http.Client{Transport: &ReplayTransport{Dir: dir}}}
+	replayed, err := Diff(context.Background(), operations, replaying, DiffOptions{})
+	if err != nil {
+		t.Fatalf("Diff() with replay error = %v", err)
+	}
+
+	if !reflect.DeepEqual(replayed, recorded) {
+		t.Errorf("replayed diff = %+v, want %+v", replayed, recorded)
+	}
+}
+
+func TestReplayOtherConsistency(t *testing.T) {
+	server := newTestConsul(t)
+	dir := t.TempDir()
+
+	operations := []Operation{
+		{Node: &NodeOperation{Verb: "set", Node: map[string]interface{}{"Node": "web-001", "Address": "10.0.0.2"}}},
+	}
+
+	recording := NewConsulSource(server.URL)
+	recording.Consistency = ConsistencyStale
+	recording.Client.Transport = &RecordingTransport{Dir: dir}
+	recorded, err := Diff(context.Background(), operations, recording, DiffOptions{})
+	if err != nil {
+		t.Fatalf("Diff() with recording error = %v", err)
+	}
+
+	server.Close()
+	replaying := &ConsulSource{Addr: "http://consul.invalid:8500", Consistency: ConsistencyConsistent, Client: &http.Client{Transport: &ReplayTransport{Dir: dir}}}
+	replayed, err := Diff(context.Background(), operations, replaying, DiffOptions{})
+	if err != nil {
+		t.Fatalf("Diff() with replay error = %v", err)
+	}
+
+	if !reflect.DeepEqual(replayed.NodeModifications, recorded.NodeModifications) {
+		t.Errorf("replayed modifications = %+v, want %+v", replayed.NodeModifications, recorded.NodeModifications)
+	}
+}
+
+func TestReplayMissingResponse(t *testing.T) {
+	src := &ConsulSource{Addr: "http://consul.invalid:8500", Client: &http.Client{Transport: &ReplayTransport{Dir: t.TempDir()}}}
+
+	operations := []Operation{
+		{Node: &NodeOperation{Verb: "set", Node: map[string]interface{}{"Node": "web-001"}}},
+	}
+	_, err := Diff(context.Background(), operations, src, DiffOptions{})
+	if err == nil || !strings.Contains(err.Error(), "no recorded response for GET /v1/catalog/nodes") {
+		t.Errorf("Diff() error = %v, want a missing recording error", err)
+	}
+}
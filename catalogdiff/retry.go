@@ -0,0 +1,131 @@
+package catalogdiff
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// retryBaseWait is the wait before the first retry, doubled for each retry
+const retryBaseWait = 250 * time.Millisecond
+
+// defaultRetryMaxWait caps waits when RetryPolicy.MaxWait is not set
+const defaultRetryMaxWait = 10 * time.Second
+
+// RetryPolicy controls how requests to Consul are retried on connection
+// errors and 429, 500 and 503 responses, for example during leader elections
+type RetryPolicy struct {
+	Retries int           // Retries after the first attempt; 0 disables retrying
+	MaxWait time.Duration // Cap of a single wait, including Retry-After; 10s if 0
+}
+
+// wait returns the wait before retry n (from 0): exponential backoff with
+// jitter, or the server's Retry-After, capped by MaxWait
+func (p RetryPolicy) wait(n int, resp *http.Response) time.Duration {
+	maxWait := p.MaxWait
+	if maxWait <= 0 {
+		maxWait = defaultRetryMaxWait
+	}
+
+	if resp != nil {
+		if d, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+			return min(d, maxWait)
+		}
+	}
+
+	backoff := maxWait
+	if n < 32 && retryBaseWait<<n < maxWait {
+		backoff = retryBaseWait << n
+	}
+	// Spread retries of concurrent runs over the upper half of the backoff
+	return backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+}
+
+// parseRetryAfter parses a Retry-After header in seconds or as an HTTP date
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(value); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		return max(time.Until(t), 0), true
+	}
+	return 0, false
+}
+
+// isRetryableStatus reports whether a response status is worth retrying
+func isRetryableStatus(code int) bool {
+	switch code {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusServiceUnavailable:
+		return true
+	}
+	return false
+}
+
+// isRetryableError reports whether a request error is a connection error,
+// as opposed to a cancellation or an error of the transport itself
+func isRetryableError(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	// The client wraps every error in a *url.Error, which is a net.Error too
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		err = urlErr.Err
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// doWithRetry sends a request, retrying according to the policy. The last
+// response is returned even if its status is retryable.
+func (s *ConsulSource) doWithRetry(ctx context.Context, req *http.Request) (*http.Response, error) {
+	for retry := 0; ; retry++ {
+		resp, err := s.Client.Do(req)
+
+		var reason string
+		switch {
+		case err != nil && isRetryableError(ctx, err):
+			reason = err.Error()
+		case err == nil && isRetryableStatus(resp.StatusCode):
+			reason = fmt.Sprintf("consul returned status %d", resp.StatusCode)
+		default:
+			if err == nil && retry > 0 {
+				logf(s.Logger, "[INFO] GET %s succeeded after %d retries", req.URL.Path, retry)
+			}
+			return resp, err
+		}
+
+		if retry >= s.Retry.Retries {
+			if s.Retry.Retries > 0 {
+				logf(s.Logger, "[WARN] GET %s failed after %d retries", req.URL.Path, retry)
+			}
+			return resp, err
+		}
+
+		wait := s.Retry.wait(retry, resp)
+		if resp != nil {
+			// Drain the body so the connection can be reused
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		logf(s.Logger, "[WARN] GET %s: %s, retrying in %s (retry %d of %d)", req.URL.Path, reason, wait.Round(time.Millisecond), retry+1, s.Retry.Retries)
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
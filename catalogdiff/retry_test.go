@@ -0,0 +1,144 @@
+package catalogdiff
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newFlakyConsul starts a fake Consul whose node list fails the first
+// failures calls with fail, and counts the calls
+func newFlakyConsul(t *testing.T, failures int, fail func(w http.ResponseWriter)) (*httptest.Server, *int32) {
+	t.Helper()
+
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if int(atomic.AddInt32(&calls, 1)) <= failures {
+			fail(w)
+			return
+		}
+		w.Write([]byte(`[{"Node":"web-001","Address":"10.0.0.1"}]`))
+	}))
+	t.Cleanup(server.Close)
+	return server, &calls
+}
+
+func TestConsulSourceRetry(t *testing.T) {
+	status := func(code int) func(w http.ResponseWriter) {
+		return func(w http.ResponseWriter) { w.WriteHeader(code) }
+	}
+
+	tests := []struct {
+		name      string
+		failures  int
+		fail      func(w http.ResponseWriter)
+		retries   int
+		wantCalls int32
+		wantErr   bool
+	}{
+		{name: "503 then success", failures: 2, fail: status(http.StatusServiceUnavailable), retries: 3, wantCalls: 3},
+		{name: "500 then success", failures: 1, fail: status(http.StatusInternalServerError), retries: 3, wantCalls: 2},
+		{
+			name:     "429 with Retry-After",
+			failures: 1,
+			fail: func(w http.ResponseWriter) {
+				w.Header().Set("Retry-After", "0")
+				w.WriteHeader(http.StatusTooManyRequests)
+			},
+			retries:   1,
+			wantCalls: 2,
+		},
+		{
+			name:     "dropped connection",
+			failures: 1,
+			fail: func(w http.ResponseWriter) {
+				conn, _, _ := w.(http.Hijacker).Hijack()
+				conn.Close()
+			},
+			retries:   1,
+			wantCalls: 2,
+		},
+		{name: "retries exhausted", failures: 5, fail: status(http.StatusServiceUnavailable), retries: 2, wantCalls: 3, wantErr: true},
+		{name: "retries disabled", failures: 1, fail: status(http.StatusServiceUnavailable), retries: 0, wantCalls: 1, wantErr: true},
+		{name: "not retryable", failures: 1, fail: status(http.StatusForbidden), retries: 3, wantCalls: 1, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, calls := newFlakyConsul(t, tt.failures, tt.fail)
+
+			src := NewConsulSource(server.URL)
+			src.Retry = RetryPolicy{Retries: tt.retries, MaxWait: time.Millisecond}
+
+			nodes, err := src.fetchNodes(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("fetchNodes() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && len(nodes) != 1 {
+				t.Errorf("fetchNodes() = %+v, want web-001", nodes)
+			}
+			if got := atomic.LoadInt32(calls); got != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", got, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestConsulSourceRetryCanceled(t *testing.T) {
+	server, calls := newFlakyConsul(t, 5, func(w http.ResponseWriter) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+
+	src := NewConsulSource(server.URL)
+	src.Retry = RetryPolicy{Retries: 5, MaxWait: time.Minute}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if _, err := src.fetchNodes(ctx); err == nil {
+		t.Fatal("fetchNodes() succeeded, want error")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("fetchNodes() returned after %s, want it to stop waiting on cancellation", elapsed)
+	}
+	if got := atomic.LoadInt32(calls); got != 1 {
+		t.Errorf("calls = %d, want 1", got)
+	}
+}
+
+func TestRetryPolicyWait(t *testing.T) {
+	policy := RetryPolicy{Retries: 5, MaxWait: 2 * time.Second}
+
+	tests := []struct {
+		name       string
+		retry      int
+		retryAfter string
+		min, max   time.Duration
+	}{
+		{name: "first retry", retry: 0, min: 125 * time.Millisecond, max: 250 * time.Millisecond},
+		{name: "third retry", retry: 2, min: 500 * time.Millisecond, max: time.Second},
+		{name: "capped backoff", retry: 10, min: time.Second, max: 2 * time.Second},
+		{name: "Retry-After seconds", retry: 0, retryAfter: "1", min: time.Second, max: time.Second},
+		{name: "Retry-After capped", retry: 0, retryAfter: "120", min: 2 * time.Second, max: 2 * time.Second},
+		{name: "Retry-After date in the past", retry: 3, retryAfter: "Mon, 02 Jan 2006 15:04:05 GMT", min: 0, max: 0},
+		{name: "invalid Retry-After", retry: 0, retryAfter: "soon", min: 125 * time.Millisecond, max: 250 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{Header: http.Header{}}
+			if tt.retryAfter != "" {
+				resp.Header.Set("Retry-After", tt.retryAfter)
+			}
+			for i := 0; i < 20; i++ {
+				if got := policy.wait(tt.retry, resp); got < tt.min || got > tt.max {
+					t.Fatalf("wait(%d) = %s, want between %s and %s", tt.retry, got, tt.min, tt.max)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,171 @@
+package catalogdiff
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Pattern matches names either as a glob or, when wrapped in slashes
+// (e.g. "/^web-[0-9]+$/"), as a regular expression
+type Pattern struct {
+	raw string
+	re  *regexp.Regexp
+}
+
+// CompilePattern parses a glob or /regex/ pattern
+func CompilePattern(s string) (*Pattern, error) {
+	if len(s) >= 2 && strings.HasPrefix(s, "/") && strings.HasSuffix(s, "/") {
+		re, err := regexp.Compile(s[1 : len(s)-1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression %q: %w", s, err)
+		}
+		return &Pattern{raw: s, re: re}, nil
+	}
+
+	if _, err := path.Match(s, ""); err != nil {
+		return nil, fmt.Errorf("invalid glob %q: %w", s, err)
+	}
+	return &Pattern{raw: s}, nil
+}
+
+// Match checks if a name matches the pattern
+func (p *Pattern) Match(name string) bool {
+	if p.re != nil {
+		return p.re.MatchString(name)
+	}
+	matched, _ := path.Match(p.raw, name)
+	return matched
+}
+
+// String returns the pattern as given
+func (p *Pattern) String() string {
+	return p.raw
+}
+
+// Selector restricts the targets considered to those matching every set criterion
+type Selector struct {
+	Node     *Pattern
+	Service  *Pattern // Matched against the service ID or name
+	NodeMeta map[string]*Pattern
+	Tag      *Pattern // Matches if any service tag matches
+}
+
+// IsEmpty returns true if no criteria are set
+func (s Selector) IsEmpty() bool {
+	return s.Node == nil && s.Service == nil && len(s.NodeMeta) == 0 && s.Tag == nil
+}
+
+// AddNodeMeta parses and adds a key=value node metadata criterion
+func (s *Selector) AddNodeMeta(value string) error {
+	key, pattern, ok := strings.Cut(value, "=")
+	if !ok || key == "" {
+		return fmt.Errorf("expected key=value, got %q", value)
+	}
+
+	p, err := CompilePattern(pattern)
+	if err != nil {
+		return err
+	}
+
+	if s.NodeMeta == nil {
+		s.NodeMeta = make(map[string]*Pattern)
+	}
+	s.NodeMeta[key] = p
+	return nil
+}
+
+// matchesNode checks the node name and metadata criteria
+func (s Selector) matchesNode(name string, meta map[string]string) bool {
+	if s.Node != nil && !s.Node.Match(name) {
+		return false
+	}
+	for key, p := range s.NodeMeta {
+		value, ok := meta[key]
+		if !ok || !p.Match(value) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchesService checks the service ID/name and tag criteria
+func (s Selector) matchesService(id, name string, tags []string) bool {
+	if s.Service != nil && !s.Service.Match(id) && !s.Service.Match(name) {
+		return false
+	}
+	if s.Tag != nil {
+		for _, tag := range tags {
+			if s.Tag.Match(tag) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// SelectOperations returns the operations whose targets match the selector.
+// Node metadata is taken from node operations in the payload. Node
+// operations are dropped when service criteria are set. Check operations
+// match on their node, and when service criteria are set, on the service
+// they belong to, so node-level checks are dropped.
+func SelectOperations(operations []Operation, sel Selector) []Operation {
+	if sel.IsEmpty() {
+		return operations
+	}
+
+	nodeMeta := make(map[string]map[string]string)
+	for _, op := range operations {
+		if op.Node == nil {
+			continue
+		}
+		nodeName, nodeData := extractNodeInfo(op.Node.Node)
+		nodeMeta[nodeName] = stringMap(nodeData["Meta"])
+	}
+
+	serviceCriteria := sel.Service != nil || sel.Tag != nil
+
+	var selected []Operation
+	for _, op := range operations {
+		switch {
+		case op.Node != nil:
+			nodeName, _ := extractNodeInfo(op.Node.Node)
+			if !serviceCriteria && sel.matchesNode(nodeName, nodeMeta[nodeName]) {
+				selected = append(selected, op)
+			}
+
+		case op.Service != nil:
+			nodeName, serviceID, serviceData := extractServiceInfo(op.Service)
+			name, _ := serviceData["Service"].(string)
+			if sel.matchesNode(nodeName, nodeMeta[nodeName]) &&
+				sel.matchesService(serviceID, name, stringSlice(serviceData["Tags"])) {
+				selected = append(selected, op)
+			}
+
+		case op.Check != nil:
+			if !sel.matchesNode(op.Check.Node, nodeMeta[op.Check.Node]) {
+				continue
+			}
+			serviceID, _ := op.Check.Check["ServiceID"].(string)
+			name, _ := op.Check.Check["ServiceName"].(string)
+			if !serviceCriteria ||
+				(serviceID != "" && sel.matchesService(serviceID, name, stringSlice(op.Check.Check["ServiceTags"]))) {
+				selected = append(selected, op)
+			}
+		}
+	}
+
+	return selected
+}
+
+// stringMap converts a JSON object to a map of strings
+func stringMap(v interface{}) map[string]string {
+	m, _ := v.(map[string]interface{})
+	result := make(map[string]string, len(m))
+	for k, val := range m {
+		result[k] = fmt.Sprint(val)
+	}
+	return result
+}
@@ -0,0 +1,275 @@
+package catalogdiff
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Raft message types of the records in a snapshot's state store. Consul sets
+// snapshotIgnoreUnknownTypeFlag on types older versions may skip.
+const (
+	snapshotRegisterType          = 0
+	snapshotKVSType               = 1
+	snapshotIgnoreUnknownTypeFlag = 128
+)
+
+// SnapshotSource reads the state from a snapshot archive written by
+// `consul snapshot save`, so a diff can run against a point-in-time backup
+// without network access. Key/value entries in the snapshot are counted but
+// not diffed.
+type SnapshotSource struct {
+	Path   string
+	Logger *log.Logger // Receives a summary of the snapshot; nil discards it
+}
+
+// snapshotMeta holds the fields of the archive's meta.json used for logging
+type snapshotMeta struct {
+	ID    string `json:"ID"`
+	Index uint64 `json:"Index"`
+}
+
+// FetchState reads the whole catalog from the snapshot; targets not
+// referenced by operations are ignored by the diff
+func (s *SnapshotSource) FetchState(ctx context.Context, operations []Operation) (*ConsulState, error) {
+	f, err := os.Open(s.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open snapshot: %w", err)
+	}
+	defer f.Close()
+
+	state, err := readSnapshot(f, s.Logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot %s: %w", s.Path, err)
+	}
+	return state, nil
+}
+
+// readSnapshot reads a gzip-compressed tar archive holding meta.json,
+// state.bin and SHA256SUMS, verifying the checksums of the other files
+func readSnapshot(r io.Reader, logger *log.Logger) (*ConsulState, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("not a snapshot archive: %w", err)
+	}
+	defer gz.Close()
+
+	var state *ConsulState
+	var sums string
+	hashes := make(map[string]string)
+
+	archive := tar.NewReader(gz)
+	for {
+		hdr, err := archive.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read archive: %w", err)
+		}
+
+		h := sha256.New()
+		body := io.TeeReader(archive, h)
+
+		switch hdr.Name {
+		case "meta.json":
+			var meta snapshotMeta
+			if err := json.NewDecoder(body).Decode(&meta); err != nil {
+				return nil, fmt.Errorf("failed to parse meta.json: %w", err)
+			}
+			logf(logger, "[INFO] Reading snapshot %s taken at index %d", meta.ID, meta.Index)
+
+		case "state.bin":
+			if state, err = readSnapshotState(body, logger); err != nil {
+				return nil, fmt.Errorf("failed to parse state.bin: %w", err)
+			}
+
+		case "SHA256SUMS":
+			data, err := io.ReadAll(archive)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read SHA256SUMS: %w", err)
+			}
+			sums = string(data)
+			continue
+		}
+
+		// Hash the rest of the file, which the decoders may not have read
+		if _, err := io.Copy(io.Discard, body); err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", hdr.Name, err)
+		}
+		hashes[hdr.Name] = hex.EncodeToString(h.Sum(nil))
+	}
+
+	if state == nil {
+		return nil, fmt.Errorf("archive has no state.bin")
+	}
+	if err := verifySnapshotSums(sums, hashes); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+// snapshotSummedFiles lists the files SHA256SUMS must cover, so that a
+// truncated or edited SHA256SUMS cannot leave the state unverified
+var snapshotSummedFiles = []string{"meta.json", "state.bin"}
+
+// verifySnapshotSums checks the archive's files against SHA256SUMS, which
+// lists "<hex digest>  <name>" lines
+func verifySnapshotSums(sums string, hashes map[string]string) error {
+	if sums == "" {
+		return fmt.Errorf("archive has no SHA256SUMS")
+	}
+
+	listed := make(map[string]bool)
+	for _, line := range strings.Split(strings.TrimSpace(sums), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return fmt.Errorf("invalid SHA256SUMS line %q", line)
+		}
+
+		want, name := fields[0], fields[1]
+		got, ok := hashes[name]
+		if !ok {
+			return fmt.Errorf("SHA256SUMS lists %s, which is not in the archive", name)
+		}
+		if got != want {
+			return fmt.Errorf("checksum mismatch for %s", name)
+		}
+		listed[name] = true
+	}
+
+	for _, name := range snapshotSummedFiles {
+		if !listed[name] {
+			return fmt.Errorf("SHA256SUMS does not list %s", name)
+		}
+	}
+	return nil
+}
+
+// readSnapshotState decodes the state store records of state.bin: a
+// msgpack header followed by records of a message type byte and a msgpack
+// encoded request
+func readSnapshotState(r io.Reader, logger *log.Logger) (*ConsulState, error) {
+	dec := newMsgpackDecoder(r)
+
+	// The header holds the Raft index the snapshot was taken at
+	if _, err := dec.decode(); err != nil {
+		return nil, fmt.Errorf("failed to decode header: %w", err)
+	}
+
+	state := normalizeState(&ConsulState{})
+	kvEntries, skipped := 0, 0
+
+	for {
+		msgType, err := dec.r.ReadByte()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		record, err := dec.decodeValue()
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode record of type %d: %w", msgType, err)
+		}
+
+		switch msgType &^ snapshotIgnoreUnknownTypeFlag {
+		case snapshotRegisterType:
+			req, ok := record.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("invalid register record")
+			}
+			addSnapshotRegistration(state, req, logger)
+		case snapshotKVSType:
+			kvEntries++
+		default:
+			skipped++
+		}
+	}
+
+	// Match the order of the Consul API sources
+	for _, services := range state.Services {
+		sort.Slice(services, func(i, j int) bool {
+			return services[i].ID < services[j].ID
+		})
+	}
+	for _, checks := range state.Checks {
+		sort.Slice(checks, func(i, j int) bool {
+			return checks[i].CheckID < checks[j].CheckID
+		})
+	}
+
+	logf(logger, "[INFO] Snapshot holds %d nodes; skipped %d KV entries and %d other records", len(state.Nodes), kvEntries, skipped)
+	return state, nil
+}
+
+// addSnapshotRegistration adds the node, service and checks of a register
+// request to the state. Consul writes one request per node, followed by one
+// per service and check of the node.
+func addSnapshotRegistration(state *ConsulState, req map[string]interface{}, logger *log.Logger) {
+	// Imported nodes of cluster peers are not part of the local catalog
+	if peer, _ := req["PeerName"].(string); peer != "" {
+		return
+	}
+
+	nodeName, _ := req["Node"].(string)
+	if nodeName == "" {
+		return
+	}
+
+	if _, ok := state.Nodes[nodeName]; !ok {
+		var node ConsulNode
+		decodeSnapshotObject(map[string]interface{}{
+			"ID":              req["ID"],
+			"Node":            nodeName,
+			"Address":         req["Address"],
+			"Datacenter":      req["Datacenter"],
+			"TaggedAddresses": req["TaggedAddresses"],
+			"Meta":            req["NodeMeta"],
+			"CreateIndex":     req["CreateIndex"],
+			"ModifyIndex":     req["ModifyIndex"],
+		}, &node, logger)
+		state.Nodes[nodeName] = node
+		state.Services[nodeName] = []ConsulService{}
+	}
+
+	if svc, ok := req["Service"].(map[string]interface{}); ok {
+		var service ConsulService
+		decodeSnapshotObject(svc, &service, logger)
+		state.Services[nodeName] = append(state.Services[nodeName], service)
+	}
+
+	checks, _ := req["Checks"].([]interface{})
+	if check, ok := req["Check"].(map[string]interface{}); ok {
+		checks = append(checks, check)
+	}
+	for _, c := range checks {
+		var check ConsulCheck
+		decodeSnapshotObject(c, &check, logger)
+		check.Node = nodeName
+		state.Checks[nodeName] = append(state.Checks[nodeName], check)
+	}
+}
+
+// decodeSnapshotObject converts a decoded msgpack object to a catalog type,
+// whose JSON field names match Consul's Go field names. Fields of
+// unexpected types are left unset.
+func decodeSnapshotObject(v interface{}, target interface{}, logger *log.Logger) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	if err := json.Unmarshal(data, target); err != nil {
+		logf(logger, "[WARN] Snapshot record has unexpected field types: %v", err)
+	}
+}
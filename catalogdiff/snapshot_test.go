@@ -0,0 +1,283 @@
+package catalogdiff
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// encodeMsgpack appends v to buf in the subset of MessagePack that Consul's
+// encoder produces for the types used in the tests
+func encodeMsgpack(buf *bytes.Buffer, v interface{}) {
+	switch val := v.(type) {
+	case nil:
+		buf.WriteByte(0xc0)
+	case bool:
+		if val {
+			buf.WriteByte(0xc3)
+		} else {
+			buf.WriteByte(0xc2)
+		}
+	case int:
+		if val >= 0 && val < 128 {
+			buf.WriteByte(byte(val))
+			return
+		}
+		buf.WriteByte(0xd3)
+		binary.Write(buf, binary.BigEndian, int64(val))
+	case string:
+		if len(val) < 32 {
+			buf.WriteByte(0xa0 | byte(len(val)))
+		} else {
+			buf.WriteByte(0xd9)
+			buf.WriteByte(byte(len(val)))
+		}
+		buf.WriteString(val)
+	case []string:
+		items := make([]interface{}, len(val))
+		for i, s := range val {
+			items[i] = s
+		}
+		encodeMsgpack(buf, items)
+	case []interface{}:
+		buf.WriteByte(0xdc)
+		binary.Write(buf, binary.BigEndian, uint16(len(val)))
+		for _, item := range val {
+			encodeMsgpack(buf, item)
+		}
+	case map[string]interface{}:
+		keys := make([]string, 0, len(val))
+		for k := range val {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		buf.WriteByte(0xde)
+		binary.Write(buf, binary.BigEndian, uint16(len(val)))
+		for _, k := range keys {
+			encodeMsgpack(buf, k)
+			encodeMsgpack(buf, val[k])
+		}
+	default:
+		panic(fmt.Sprintf("unsupported type %T", v))
+	}
+}
+
+// snapshotRecord is a state store record of a test snapshot
+type snapshotRecord struct {
+	msgType byte
+	body    map[string]interface{}
+}
+
+// writeTestSnapshot writes a snapshot archive with the records to a file,
+// corrupting the checksum of state.bin if requested
+func writeTestSnapshot(t *testing.T, records []snapshotRecord, badSum bool) string {
+	t.Helper()
+
+	var state bytes.Buffer
+	encodeMsgpack(&state, map[string]interface{}{"LastIndex": 42})
+	for _, r := range records {
+		state.WriteByte(r.msgType)
+		encodeMsgpack(&state, r.body)
+	}
+
+	files := []struct {
+		name string
+		data []byte
+	}{
+		{"meta.json", []byte(`{"ID":"2-42-1700000000000","Size":1,"Index":42,"Term":2,"Version":1}`)},
+		{"state.bin", state.Bytes()},
+	}
+
+	var sums strings.Builder
+	for _, f := range files {
+		sum := sha256.Sum256(f.data)
+		if badSum && f.name == "state.bin" {
+			sum[0]++
+		}
+		fmt.Fprintf(&sums, "%x  %s\n", sum, f.name)
+	}
+	files = append(files, struct {
+		name string
+		data []byte
+	}{"SHA256SUMS", []byte(sums.String())})
+
+	var archive bytes.Buffer
+	gz := gzip.NewWriter(&archive)
+	tw := tar.NewWriter(gz)
+	for _, f := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: f.name, Mode: 0600, Size: int64(len(f.data))}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write(f.data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "backup.snap")
+	if err := os.WriteFile(path, archive.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// testSnapshotRecords mirrors how Consul persists a node: a register request
+// for the node, then the same request with each service and check set
+func testSnapshotRecords() []snapshotRecord {
+	node := func(extra map[string]interface{}) map[string]interface{} {
+		req := map[string]interface{}{
+			"ID":              "4e1c6e2a-0000-0000-0000-000000000001",
+			"Node":            "web-001",
+			"Address":         "10.0.0.1",
+			"Datacenter":      "dc1",
+			"TaggedAddresses": map[string]interface{}{"lan": "10.0.0.1"},
+			"NodeMeta":        map[string]interface{}{"env": "prod"},
+			"CreateIndex":     5,
+			"ModifyIndex":     300,
+			"Service":         nil,
+			"Check":           nil,
+		}
+		for k, v := range extra {
+			req[k] = v
+		}
+		return req
+	}
+
+	return []snapshotRecord{
+		{snapshotRegisterType, node(nil)},
+		{snapshotRegisterType, node(map[string]interface{}{"Service": map[string]interface{}{
+			"ID": "redis", "Service": "redis", "Port": 6379, "Tags": []string{}, "CreateIndex": 8, "ModifyIndex": 8,
+		}})},
+		{snapshotRegisterType, node(map[string]interface{}{"Service": map[string]interface{}{
+			"ID": "nginx", "Service": "nginx", "Port": 80, "Tags": []string{"web"},
+			"Weights": map[string]interface{}{"Passing": 1, "Warning": 1}, "Meta": map[string]interface{}{"version": "1.25"},
+		}})},
+		{snapshotRegisterType, node(map[string]interface{}{"Check": map[string]interface{}{
+			"Node": "web-001", "CheckID": "serfHealth", "Name": "Serf Health Status", "Status": "passing",
+		}})},
+		{snapshotKVSType, map[string]interface{}{"Op": "set", "DirEnt": map[string]interface{}{"Key": "app/config", "Value": "x"}}},
+		{snapshotRegisterType, map[string]interface{}{"Node": "web-009", "Address": "10.9.0.1", "PeerName": "dc2-peer"}},
+		{snapshotIgnoreUnknownTypeFlag | 37, map[string]interface{}{"Unknown": true}},
+	}
+}
+
+func TestSnapshotSource(t *testing.T) {
+	path := writeTestSnapshot(t, testSnapshotRecords(), false)
+
+	state, err := (&SnapshotSource{Path: path}).FetchState(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("FetchState() error = %v", err)
+	}
+
+	want := &ConsulState{
+		Nodes: map[string]ConsulNode{
+			"web-001": {
+				ID:              "4e1c6e2a-0000-0000-0000-000000000001",
+				Node:            "web-001",
+				Address:         "10.0.0.1",
+				Datacenter:      "dc1",
+				TaggedAddresses: map[string]string{"lan": "10.0.0.1"},
+				Meta:            map[string]string{"env": "prod"},
+				CreateIndex:     5,
+				ModifyIndex:     300,
+			},
+		},
+		Services: map[string][]ConsulService{
+			"web-001": {
+				{ID: "nginx", Service: "nginx", Port: 80, Tags: []string{"web"}, Weights: &ServiceWeights{Passing: 1, Warning: 1}, Meta: map[string]string{"version": "1.25"}},
+				{ID: "redis", Service: "redis", Port: 6379, Tags: []string{}, CreateIndex: 8, ModifyIndex: 8},
+			},
+		},
+		Checks: map[string][]ConsulCheck{
+			"web-001": {{Node: "web-001", CheckID: "serfHealth", Name: "Serf Health Status", Status: "passing"}},
+		},
+	}
+	if !reflect.DeepEqual(state, want) {
+		t.Errorf("FetchState() = %+v, want %+v", state, want)
+	}
+
+	operations := []Operation{
+		{Node: &NodeOperation{Verb: "set", Node: map[string]interface{}{"Node": "web-001", "Address": "10.0.0.1", "Meta": map[string]interface{}{"env": "prod"}}}},
+		{Service: &ServiceOperation{Verb: "set", Node: "web-001", Service: map[string]interface{}{"ID": "nginx", "Port": float64(8080)}}},
+	}
+	diff, err := Diff(context.Background(), operations, &SnapshotSource{Path: path}, DiffOptions{})
+	if err != nil {
+		t.Fatalf("Diff() error = %v", err)
+	}
+	if diff.TotalChanges() != 1 || len(diff.ServiceModifications) != 1 {
+		t.Errorf("Diff() = %+v, want only the nginx port modification", diff)
+	}
+}
+
+func TestSnapshotSourceErrors(t *testing.T) {
+	notArchive := filepath.Join(t.TempDir(), "state.json")
+	if err := os.WriteFile(notArchive, []byte(`{}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		wantErr string
+	}{
+		{name: "checksum mismatch", path: writeTestSnapshot(t, testSnapshotRecords(), true), wantErr: "checksum mismatch for state.bin"},
+		{name: "not an archive", path: notArchive, wantErr: "not a snapshot archive"},
+		{name: "missing file", path: filepath.Join(t.TempDir(), "missing.snap"), wantErr: "failed to open snapshot"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := (&SnapshotSource{Path: tt.path}).FetchState(context.Background(), nil)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("FetchState() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestVerifySnapshotSums(t *testing.T) {
+	hashes := map[string]string{"meta.json": "aa", "state.bin": "bb"}
+
+	tests := []struct {
+		name    string
+		sums    string
+		wantErr string
+	}{
+		{name: "all files listed", sums: "aa  meta.json\nbb  state.bin\n"},
+		{name: "state.bin not listed", sums: "aa  meta.json\n", wantErr: "SHA256SUMS does not list state.bin"},
+		{name: "meta.json not listed", sums: "bb  state.bin\n", wantErr: "SHA256SUMS does not list meta.json"},
+		{name: "listed file missing", sums: "aa  meta.json\nbb  state.bin\ncc  extra.bin\n", wantErr: "extra.bin, which is not in the archive"},
+		{name: "checksum mismatch", sums: "aa  meta.json\ncc  state.bin\n", wantErr: "checksum mismatch for state.bin"},
+		{name: "empty", sums: "", wantErr: "archive has no SHA256SUMS"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := verifySnapshotSums(tt.sums, hashes)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("verifySnapshotSums() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("verifySnapshotSums() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package catalogdiff
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// StateSource provides the current catalog state of the targets of operations
+type StateSource interface {
+	FetchState(ctx context.Context, operations []Operation) (*ConsulState, error)
+}
+
+// FileSource reads the state from a JSON catalog dump written by SaveState,
+// for example to diff against a snapshot captured earlier or exported from
+// an air-gapped cluster
+type FileSource struct {
+	Path string
+}
+
+// FetchState loads the whole dump; targets not referenced by operations are
+// ignored by the diff
+func (s *FileSource) FetchState(ctx context.Context, operations []Operation) (*ConsulState, error) {
+	data, err := os.ReadFile(s.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state file: %w", err)
+	}
+
+	var state ConsulState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to parse state file %s: %w", s.Path, err)
+	}
+
+	return normalizeState(&state), nil
+}
+
+// MemorySource provides a fixed state, for tests and callers that build the
+// state themselves
+type MemorySource struct {
+	State *ConsulState
+}
+
+// FetchState returns the fixed state
+func (s *MemorySource) FetchState(ctx context.Context, operations []Operation) (*ConsulState, error) {
+	if s.State == nil {
+		return normalizeState(&ConsulState{}), nil
+	}
+	return normalizeState(s.State), nil
+}
+
+// SaveState writes a state as a JSON catalog dump readable by FileSource
+func SaveState(path string, state *ConsulState) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode state: %w", err)
+	}
+
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	return nil
+}
+
+// normalizeState initializes the maps of a state that are missing
+func normalizeState(state *ConsulState) *ConsulState {
+	if state.Nodes == nil {
+		state.Nodes = make(map[string]ConsulNode)
+	}
+	if state.Services == nil {
+		state.Services = make(map[string][]ConsulService)
+	}
+	if state.Checks == nil {
+		state.Checks = make(map[string][]ConsulCheck)
+	}
+	return state
+}
@@ -0,0 +1,87 @@
+package catalogdiff
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestFileSourceRoundTrip(t *testing.T) {
+	state := &ConsulState{
+		Nodes: map[string]ConsulNode{
+			"web-001": {Node: "web-001", Address: "10.0.0.1", Meta: map[string]string{"env": "prod"}},
+		},
+		Services: map[string][]ConsulService{
+			"web-001": {{ID: "nginx", Service: "nginx", Port: 80, Tags: []string{"web"}}},
+		},
+		Checks: map[string][]ConsulCheck{
+			"web-001": {{Node: "web-001", CheckID: "serfHealth", Status: "passing"}},
+		},
+	}
+
+	path := filepath.Join(t.TempDir(), "state.json")
+	if err := SaveState(path, state); err != nil {
+		t.Fatalf("SaveState() error = %v", err)
+	}
+
+	loaded, err := (&FileSource{Path: path}).FetchState(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("FetchState() error = %v", err)
+	}
+	if !reflect.DeepEqual(loaded, state) {
+		t.Errorf("FetchState() = %+v, want %+v", loaded, state)
+	}
+
+	if _, err := (&FileSource{Path: filepath.Join(t.TempDir(), "missing.json")}).FetchState(context.Background(), nil); err == nil {
+		t.Error("FetchState() for a missing file succeeded, want error")
+	}
+}
+
+func TestDiffMemorySource(t *testing.T) {
+	src := &MemorySource{State: &ConsulState{
+		Nodes: map[string]ConsulNode{"web-001": {Node: "web-001", Address: "10.0.0.1"}},
+	}}
+
+	operations := []Operation{
+		{Node: &NodeOperation{Verb: "set", Node: map[string]interface{}{"Node": "web-001", "Address": "10.0.0.1"}}},
+		{Node: &NodeOperation{Verb: "set", Node: map[string]interface{}{"Node": "web-002", "Address": "10.0.0.2"}}},
+	}
+
+	diff, err := Diff(context.Background(), operations, src, DiffOptions{})
+	if err != nil {
+		t.Fatalf("Diff() error = %v", err)
+	}
+	if diff.TotalChanges() != 1 || len(diff.NodeAdditions) != 1 || diff.NodeAdditions[0].Node != "web-002" {
+		t.Errorf("Diff() = %+v, want only the addition of web-002", diff)
+	}
+}
+
+func TestDiffStateFetched(t *testing.T) {
+	state := &ConsulState{
+		Nodes: map[string]ConsulNode{"web-001": {Node: "web-001", Address: "10.0.0.1"}},
+	}
+	operations := []Operation{
+		{Node: &NodeOperation{Verb: "set", Node: map[string]interface{}{"Node": "web-001", "Address": "10.0.0.2"}}},
+	}
+
+	var fetched *ConsulState
+	opts := DiffOptions{StateFetched: func(s *ConsulState) error {
+		fetched = s
+		return nil
+	}}
+	if _, err := Diff(context.Background(), operations, &MemorySource{State: state}, opts); err != nil {
+		t.Fatalf("Diff() error = %v", err)
+	}
+	if fetched != state {
+		t.Errorf("StateFetched got %+v, want the source's state", fetched)
+	}
+
+	errSave := errors.New("save failed")
+	opts.StateFetched = func(*ConsulState) error { return errSave }
+	diff, err := Diff(context.Background(), operations, &MemorySource{State: state}, opts)
+	if !errors.Is(err, errSave) || diff != nil {
+		t.Errorf("Diff() = %+v, %v, want the StateFetched error", diff, err)
+	}
+}
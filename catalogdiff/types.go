@@ -0,0 +1,286 @@
+package catalogdiff
+
+import (
+	"log"
+	"strings"
+)
+
+// Operation represents a single Consul operation
+type Operation struct {
+	Node    *NodeOperation    `json:"Node,omitempty"`
+	Service *ServiceOperation `json:"Service,omitempty"`
+	Check   *CheckOperation   `json:"Check,omitempty"`
+
+	Line    int      `json:"-"` // Line in the input file where the operation starts
+	Unknown []string `json:"-"` // Unrecognized keys, e.g. "Session" or "Node.Foo"
+}
+
+// NodeOperation represents a node operation
+type NodeOperation struct {
+	Verb string                 `json:"Verb"`
+	Node map[string]interface{} `json:"Node"`
+}
+
+// ServiceOperation represents a service operation
+type ServiceOperation struct {
+	Verb    string                 `json:"Verb"`
+	Node    string                 `json:"Node"`
+	Service map[string]interface{} `json:"Service"`
+}
+
+// CheckOperation represents a check operation
+type CheckOperation struct {
+	Verb  string                 `json:"Verb"`
+	Node  string                 `json:"Node"`
+	Check map[string]interface{} `json:"Check"`
+}
+
+// ConsulState represents the current state in Consul
+type ConsulState struct {
+	Nodes    map[string]ConsulNode      `json:"Nodes"`    // By node name
+	Services map[string][]ConsulService `json:"Services"` // By node name
+	Checks   map[string][]ConsulCheck   `json:"Checks"`   // By node name
+}
+
+// ConsulNode represents a node in Consul
+type ConsulNode struct {
+	ID              string            `json:"ID"`
+	Node            string            `json:"Node"`
+	Address         string            `json:"Address"`
+	Datacenter      string            `json:"Datacenter"`
+	TaggedAddresses map[string]string `json:"TaggedAddresses"`
+	Meta            map[string]string `json:"Meta"`
+	CreateIndex     uint64            `json:"CreateIndex"`
+	ModifyIndex     uint64            `json:"ModifyIndex"`
+}
+
+// ConsulService represents a service in Consul
+type ConsulService struct {
+	Kind              string                    `json:"Kind"`
+	ID                string                    `json:"ID"`
+	Service           string                    `json:"Service"`
+	Tags              []string                  `json:"Tags"`
+	Port              int                       `json:"Port"`
+	Address           string                    `json:"Address"`
+	SocketPath        string                    `json:"SocketPath"`
+	TaggedAddresses   map[string]ServiceAddress `json:"TaggedAddresses"`
+	Meta              map[string]string         `json:"Meta"`
+	Weights           *ServiceWeights           `json:"Weights"`
+	EnableTagOverride bool                      `json:"EnableTagOverride"`
+	Proxy             *ServiceProxy             `json:"Proxy"`
+	Connect           *ServiceConnect           `json:"Connect"`
+	CreateIndex       uint64                    `json:"CreateIndex"`
+	ModifyIndex       uint64                    `json:"ModifyIndex"`
+}
+
+// ServiceAddress represents a tagged address of a service
+type ServiceAddress struct {
+	Address string `json:"Address"`
+	Port    int    `json:"Port"`
+}
+
+// ServiceWeights represents the DNS weights of a service
+type ServiceWeights struct {
+	Passing int `json:"Passing"`
+	Warning int `json:"Warning"`
+}
+
+// ServiceProxy represents the Connect proxy configuration of a service
+type ServiceProxy struct {
+	DestinationServiceName string                  `json:"DestinationServiceName"`
+	DestinationServiceID   string                  `json:"DestinationServiceID"`
+	LocalServiceAddress    string                  `json:"LocalServiceAddress"`
+	LocalServicePort       int                     `json:"LocalServicePort"`
+	LocalServiceSocketPath string                  `json:"LocalServiceSocketPath"`
+	Mode                   string                  `json:"Mode"`
+	Config                 map[string]interface{}  `json:"Config"`
+	Upstreams              []ProxyUpstream         `json:"Upstreams"`
+	MeshGateway            *MeshGatewayConfig      `json:"MeshGateway"`
+	Expose                 *ExposeConfig           `json:"Expose"`
+	TransparentProxy       *TransparentProxyConfig `json:"TransparentProxy"`
+}
+
+// ProxyUpstream represents an upstream of a Connect proxy
+type ProxyUpstream struct {
+	DestinationType      string                 `json:"DestinationType"`
+	DestinationNamespace string                 `json:"DestinationNamespace"`
+	DestinationPartition string                 `json:"DestinationPartition"`
+	DestinationPeer      string                 `json:"DestinationPeer"`
+	DestinationName      string                 `json:"DestinationName"`
+	Datacenter           string                 `json:"Datacenter"`
+	LocalBindAddress     string                 `json:"LocalBindAddress"`
+	LocalBindPort        int                    `json:"LocalBindPort"`
+	LocalBindSocketPath  string                 `json:"LocalBindSocketPath"`
+	LocalBindSocketMode  string                 `json:"LocalBindSocketMode"`
+	Config               map[string]interface{} `json:"Config"`
+	MeshGateway          *MeshGatewayConfig     `json:"MeshGateway"`
+	CentrallyConfigured  bool                   `json:"CentrallyConfigured"`
+}
+
+// MeshGatewayConfig represents the mesh gateway mode of a proxy or upstream
+type MeshGatewayConfig struct {
+	Mode string `json:"Mode"`
+}
+
+// ExposeConfig represents the paths a proxy exposes without mTLS
+type ExposeConfig struct {
+	Checks bool         `json:"Checks"`
+	Paths  []ExposePath `json:"Paths"`
+}
+
+// ExposePath represents a single exposed HTTP path
+type ExposePath struct {
+	ListenerPort  int    `json:"ListenerPort"`
+	Path          string `json:"Path"`
+	LocalPathPort int    `json:"LocalPathPort"`
+	Protocol      string `json:"Protocol"`
+}
+
+// TransparentProxyConfig represents transparent proxy settings
+type TransparentProxyConfig struct {
+	OutboundListenerPort int  `json:"OutboundListenerPort"`
+	DialedDirectly       bool `json:"DialedDirectly"`
+}
+
+// ServiceConnect represents the Connect settings of a service
+type ServiceConnect struct {
+	Native         bool                   `json:"Native"`
+	SidecarService map[string]interface{} `json:"SidecarService"`
+}
+
+// ConsulCheck represents a health check in Consul
+type ConsulCheck struct {
+	Node        string   `json:"Node"`
+	CheckID     string   `json:"CheckID"`
+	Name        string   `json:"Name"`
+	Status      string   `json:"Status"`
+	Notes       string   `json:"Notes"`
+	Output      string   `json:"Output"`
+	ServiceID   string   `json:"ServiceID"`
+	ServiceName string   `json:"ServiceName"`
+	ServiceTags []string `json:"ServiceTags"`
+	Type        string   `json:"Type"`
+	CreateIndex uint64   `json:"CreateIndex"`
+	ModifyIndex uint64   `json:"ModifyIndex"`
+}
+
+// DiffResult represents the differences found
+type DiffResult struct {
+	NodeAdditions        []NodeDiff
+	NodeModifications    []NodeDiff
+	NodeDeletions        []NodeDiff
+	ServiceAdditions     []ServiceDiff
+	ServiceModifications []ServiceDiff
+	ServiceDeletions     []ServiceDiff
+	CheckDeletions       []CheckDiff
+	Conflicts            []OperationConflict // Not counted as changes
+	Ignored              []IgnoredDiff       // Not counted as changes
+	Reads                *ReadStats          // Consistency of the Consul reads, nil for other sources
+}
+
+// NodeDiff represents a node difference
+type NodeDiff struct {
+	Node     string
+	Expected map[string]interface{}
+	Current  *ConsulNode
+	Fields   []FieldDiff // For modifications
+	Line     int         // Line of the operation in the input file
+}
+
+// ServiceDiff represents a service difference
+type ServiceDiff struct {
+	Node      string
+	ServiceID string
+	Expected  map[string]interface{}
+	Current   *ConsulService
+	Fields    []FieldDiff // For modifications
+	Cascaded  bool        // Deleted implicitly by a node deletion
+	Orphan    bool        // Added to a node neither registered nor defined in the payload
+	Line      int         // Line of the operation, or of the node deletion if cascaded
+}
+
+// CheckDiff represents a check difference
+type CheckDiff struct {
+	Node     string
+	CheckID  string
+	Current  *ConsulCheck
+	Cascaded bool // Deleted implicitly by a node deletion
+	Line     int  // Line of the node deletion
+}
+
+// IgnoredDiff represents a difference suppressed by an ignore rule
+type IgnoredDiff struct {
+	Kind   string // "node", "service" or "check"
+	Target string
+	Change string // "addition", "modification" or "deletion"
+	Field  string // Empty when the whole target is ignored
+	Rule   IgnoreRule
+}
+
+// FieldDiff represents a field-level difference
+type FieldDiff struct {
+	Field    string
+	Expected interface{}
+	Current  interface{}
+}
+
+// DiffOptions controls how differences are calculated
+type DiffOptions struct {
+	// StrictFields lists top-level fields whose payload value is treated as
+	// the complete definition, so values only present in Consul are reported.
+	// "all" enables this for every field.
+	StrictFields []string
+
+	// Ignore lists rules for differences to report separately
+	Ignore []IgnoreRule
+
+	// Logger receives warnings about the operations; nil discards them
+	Logger *log.Logger
+
+	// StateFetched is called by Diff with the fetched state before it is
+	// diffed, for example to save it; an error stops Diff
+	StateFetched func(state *ConsulState) error
+}
+
+// isStrictField checks if strict checking is enabled for a top-level field
+func (o DiffOptions) isStrictField(field string) bool {
+	for _, f := range o.StrictFields {
+		if f == "all" || strings.EqualFold(f, field) {
+			return true
+		}
+	}
+	return false
+}
+
+// FormatType represents the input file format
+type FormatType int
+
+const (
+	UnknownFormat FormatType = iota
+	NDJSONTransactionFormat
+	JSONTransactionArrayFormat
+	JSONCatalogNodeFormat
+	JSONCatalogServiceFormat
+)
+
+// HasChanges returns true if there are any differences
+func (d *DiffResult) HasChanges() bool {
+	return len(d.NodeAdditions) > 0 ||
+		len(d.NodeModifications) > 0 ||
+		len(d.NodeDeletions) > 0 ||
+		len(d.ServiceAdditions) > 0 ||
+		len(d.ServiceModifications) > 0 ||
+		len(d.ServiceDeletions) > 0 ||
+		len(d.CheckDeletions) > 0
+}
+
+// TotalChanges returns the total number of changes
+func (d *DiffResult) TotalChanges() int {
+	return len(d.NodeAdditions) +
+		len(d.NodeModifications) +
+		len(d.NodeDeletions) +
+		len(d.ServiceAdditions) +
+		len(d.ServiceModifications) +
+		len(d.ServiceDeletions) +
+		len(d.CheckDeletions)
+}
@@ -0,0 +1,320 @@
+package catalogdiff
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Limits enforced by Consul on node and service metadata
+const (
+	metaMaxKeyPairs    = 64
+	metaKeyMaxLength   = 128
+	metaValueMaxLength = 512
+	metaReservedPrefix = "consul-"
+)
+
+// metaKeyPattern matches the characters Consul allows in metadata keys
+var metaKeyPattern = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// validVerbs lists the Transaction API verbs accepted for catalog operations
+var validVerbs = []string{"set", "cas", "get", "delete", "delete-cas"}
+
+// Violation represents a problem found while validating operations
+type Violation struct {
+	Line    int
+	Target  string
+	Message string
+}
+
+// ValidateOperations checks operations against the Transaction API schema
+func ValidateOperations(operations []Operation) []Violation {
+	var violations []Violation
+
+	for _, op := range operations {
+		target := describeOperation(op)
+		add := func(format string, args ...interface{}) {
+			violations = append(violations, Violation{
+				Line:    op.Line,
+				Target:  target,
+				Message: fmt.Sprintf(format, args...),
+			})
+		}
+
+		for _, field := range op.Unknown {
+			add("unknown field %q", field)
+		}
+
+		if op.Node != nil {
+			validateVerb(op.Node.Verb, add)
+			validateNodePayload(op.Node.Node, add)
+		}
+		if op.Service != nil {
+			validateVerb(op.Service.Verb, add)
+			validateServicePayload(op.Service, add)
+		}
+		if op.Check != nil {
+			validateVerb(op.Check.Verb, add)
+			validateCheckPayload(op.Check, add)
+		}
+	}
+
+	_, conflicts := ResolveOperations(operations)
+	for _, c := range conflicts {
+		for _, line := range c.Lines[1:] {
+			violations = append(violations, Violation{
+				Line:    line,
+				Target:  c.Kind + " " + c.Target,
+				Message: fmt.Sprintf("%s on the same target (first at line %d)", c.Reason, c.Lines[0]),
+			})
+		}
+	}
+
+	SortViolations(violations)
+	return violations
+}
+
+// describeOperation returns a human-readable target for an operation
+func describeOperation(op Operation) string {
+	kind, target := OperationTarget(op)
+	if kind == "" {
+		// Operations of unknown types are named after their type
+		for _, field := range op.Unknown {
+			if !strings.Contains(field, ".") {
+				return field
+			}
+		}
+		return "operation"
+	}
+	return kind + " " + target
+}
+
+// validateVerb checks that a verb is present and supported
+func validateVerb(verb string, add func(string, ...interface{})) {
+	if verb == "" {
+		add("Verb is required")
+		return
+	}
+	for _, v := range validVerbs {
+		if verb == v {
+			return
+		}
+	}
+	add("unsupported Verb %q (expected one of %s)", verb, strings.Join(validVerbs, ", "))
+}
+
+// validateNodePayload checks the fields of a node operation
+func validateNodePayload(node map[string]interface{}, add func(string, ...interface{})) {
+	if node == nil {
+		add("Node is required")
+		return
+	}
+
+	if name, ok := node["Node"].(string); !ok || name == "" {
+		add("Node.Node is required and must be a string")
+	}
+	validateStringField(node, "Address", "Node.Address", add)
+	validateStringField(node, "Datacenter", "Node.Datacenter", add)
+	validateMeta(node["Meta"], "Node.Meta", add)
+	validateStringMap(node["TaggedAddresses"], "Node.TaggedAddresses", add)
+}
+
+// validateServicePayload checks the fields of a service operation
+func validateServicePayload(serviceOp *ServiceOperation, add func(string, ...interface{})) {
+	if serviceOp.Node == "" {
+		add("Node is required")
+	}
+
+	service := serviceOp.Service
+	if service == nil {
+		add("Service is required")
+		return
+	}
+
+	id, idOK := service["ID"].(string)
+	name, nameOK := service["Service"].(string)
+	if (!idOK || id == "") && (!nameOK || name == "") {
+		add("Service.ID or Service.Service is required")
+	}
+	validateStringField(service, "ID", "Service.ID", add)
+	validateStringField(service, "Service", "Service.Service", add)
+	validateStringField(service, "Address", "Service.Address", add)
+
+	if port, ok := service["Port"]; ok {
+		if f, isNum := port.(float64); !isNum || f != float64(int(f)) {
+			add("Service.Port must be an integer, got %v", port)
+		} else if f < 0 || f > 65535 {
+			add("Service.Port must be between 0 and 65535, got %v", port)
+		}
+	}
+
+	if tags, ok := service["Tags"]; ok && tags != nil {
+		arr, isArr := tags.([]interface{})
+		if !isArr {
+			add("Service.Tags must be an array of strings")
+		} else {
+			for i, tag := range arr {
+				if _, isStr := tag.(string); !isStr {
+					add("Service.Tags[%d] must be a string, got %v", i, tag)
+				}
+			}
+		}
+	}
+
+	validateMeta(service["Meta"], "Service.Meta", add)
+}
+
+// validateCheckPayload checks the fields of a check operation
+func validateCheckPayload(checkOp *CheckOperation, add func(string, ...interface{})) {
+	check := checkOp.Check
+	if check == nil {
+		add("Check is required")
+		return
+	}
+
+	id, idOK := check["CheckID"].(string)
+	name, nameOK := check["Name"].(string)
+	if (!idOK || id == "") && (!nameOK || name == "") {
+		add("Check.CheckID or Check.Name is required")
+	}
+}
+
+// validateStringField checks that an optional field is a string
+func validateStringField(data map[string]interface{}, key, path string, add func(string, ...interface{})) {
+	value, ok := data[key]
+	if !ok || value == nil {
+		return
+	}
+	if _, isStr := value.(string); !isStr {
+		add("%s must be a string, got %v", path, value)
+	}
+}
+
+// validateStringMap checks that an optional field is a map of strings
+func validateStringMap(value interface{}, path string, add func(string, ...interface{})) map[string]interface{} {
+	if value == nil {
+		return nil
+	}
+
+	m, ok := value.(map[string]interface{})
+	if !ok {
+		add("%s must be an object", path)
+		return nil
+	}
+
+	for _, key := range sortedKeys(m) {
+		if _, isStr := m[key].(string); !isStr {
+			add("%s.%s must be a string, got %v", path, key, m[key])
+		}
+	}
+	return m
+}
+
+// validateMeta checks metadata types and the limits Consul enforces
+func validateMeta(value interface{}, path string, add func(string, ...interface{})) {
+	meta := validateStringMap(value, path, add)
+	if meta == nil {
+		return
+	}
+
+	if len(meta) > metaMaxKeyPairs {
+		add("%s has %d keys, Consul allows at most %d", path, len(meta), metaMaxKeyPairs)
+	}
+
+	for _, key := range sortedKeys(meta) {
+		switch {
+		case len(key) > metaKeyMaxLength:
+			add("%s key %q exceeds %d characters", path, key, metaKeyMaxLength)
+		case !metaKeyPattern.MatchString(key):
+			add("%s key %q may only contain alphanumerics, '-' and '_'", path, key)
+		case strings.HasPrefix(key, metaReservedPrefix):
+			add("%s key %q uses the reserved %q prefix", path, key, metaReservedPrefix)
+		}
+
+		if str, ok := meta[key].(string); ok && len(str) > metaValueMaxLength {
+			add("%s.%s value exceeds %d characters", path, key, metaValueMaxLength)
+		}
+	}
+}
+
+// ValidateNodeReferences checks that every node referenced by a service
+// operation is either defined in the payload or registered in Consul, and
+// that services are not registered on nodes the payload deletes
+func ValidateNodeReferences(ctx context.Context, src *ConsulSource, operations []Operation) ([]Violation, error) {
+	defined := make(map[string]bool)
+	deleted := make(map[string]int) // Node name to the line deleting it
+	effective, _ := ResolveOperations(operations)
+	for _, op := range effective {
+		if op.Node == nil {
+			continue
+		}
+		nodeName, _ := extractNodeInfo(op.Node.Node)
+		if verbClass(op.Node.Verb) == "delete" {
+			deleted[nodeName] = op.Line
+		} else {
+			defined[nodeName] = true
+		}
+	}
+
+	var violations []Violation
+	checked := make(map[string]bool)
+	for _, op := range operations {
+		if op.Service == nil || op.Service.Node == "" {
+			continue
+		}
+		nodeName := op.Service.Node
+		if defined[nodeName] {
+			continue
+		}
+		if line, ok := deleted[nodeName]; ok {
+			if verbClass(op.Service.Verb) != "delete" {
+				violations = append(violations, Violation{
+					Line:    op.Line,
+					Target:  describeOperation(op),
+					Message: fmt.Sprintf("node %s is deleted by the payload at line %d", nodeName, line),
+				})
+			}
+			continue
+		}
+
+		exists, ok := checked[nodeName]
+		if !ok {
+			logf(src.Logger, "[INFO] Fetching node: %s", nodeName)
+			found, err := src.NodeExists(ctx, nodeName)
+			if err != nil {
+				return nil, fmt.Errorf("failed to fetch node %s: %w", nodeName, err)
+			}
+			exists = found
+			checked[nodeName] = exists
+		}
+
+		if !exists {
+			violations = append(violations, Violation{
+				Line:    op.Line,
+				Target:  describeOperation(op),
+				Message: fmt.Sprintf("node %s is neither defined in the payload nor registered in Consul", nodeName),
+			})
+		}
+	}
+
+	return violations, nil
+}
+
+// SortViolations orders violations by line, keeping the order within a line
+func SortViolations(violations []Violation) {
+	sort.SliceStable(violations, func(i, j int) bool {
+		return violations[i].Line < violations[j].Line
+	})
+}
+
+// sortedKeys returns the keys of a map in sorted order
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
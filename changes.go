@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/zinrai/consul-catalog-diff/catalogdiff"
+)
+
+// targetChange describes the change detected for a single node, service or check
+type targetChange struct {
+	Kind     string // "node", "service" or "check"
+	Target   string // Node name, or "node/id" for services and checks
+	Change   string // "addition", "modification" or "deletion"
+	Fields   []catalogdiff.FieldDiff
+	Cascaded bool
+	Orphan   bool
+	Line     int         // Line of the operation in the input file; 0 if unknown
+	Expected interface{} // Payload of the operation; nil for cascaded deletions
+	Current  interface{} // JSON form of the current object; nil for additions
+}
+
+// Name returns the change's target as "kind/target"
+func (c targetChange) Name() string {
+	return c.Kind + "/" + c.Target
+}
+
+// Summary returns a one-line description of the change
+func (c targetChange) Summary() string {
+	var summary string
+	switch c.Change {
+	case "addition":
+		summary = fmt.Sprintf("%s %s is not registered in Consul", c.Kind, c.Target)
+	case "modification":
+		summary = fmt.Sprintf("%s %s differs from the payload in %d field(s)", c.Kind, c.Target, len(c.Fields))
+	case "deletion":
+		summary = fmt.Sprintf("%s %s is registered in Consul but deleted by the payload", c.Kind, c.Target)
+	}
+
+	if c.Orphan {
+		summary += " (orphan: node not registered)"
+	}
+	if c.Cascaded {
+		summary += " (cascaded from node deletion)"
+	}
+	return summary
+}
+
+// Details returns the field differences, one "Field: current -> expected" per line
+func (c targetChange) Details() string {
+	lines := make([]string, len(c.Fields))
+	for i, f := range c.Fields {
+		lines[i] = fmt.Sprintf("%s: %s -> %s", f.Field, formatFieldValue(f.Current), formatFieldValue(f.Expected))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// collectChanges flattens a DiffResult into per-target changes, in report order
+func collectChanges(diff *catalogdiff.DiffResult) []targetChange {
+	var changes []targetChange
+
+	addNodes := func(diffs []catalogdiff.NodeDiff, change string) {
+		for _, d := range diffs {
+			c := targetChange{
+				Kind:   "node",
+				Target: d.Node,
+				Change: change,
+				Fields: d.Fields,
+				Line:   d.Line,
+			}
+			if d.Expected != nil {
+				c.Expected = d.Expected
+			}
+			if d.Current != nil {
+				c.Current = catalogdiff.ToJSONValue(d.Current)
+			}
+			changes = append(changes, c)
+		}
+	}
+	addServices := func(diffs []catalogdiff.ServiceDiff, change string) {
+		for _, d := range diffs {
+			c := targetChange{
+				Kind:     "service",
+				Target:   fmt.Sprintf("%s/%s", d.Node, d.ServiceID),
+				Change:   change,
+				Fields:   d.Fields,
+				Cascaded: d.Cascaded,
+				Orphan:   d.Orphan,
+				Line:     d.Line,
+			}
+			if d.Expected != nil {
+				c.Expected = d.Expected
+			}
+			if d.Current != nil {
+				c.Current = catalogdiff.ToJSONValue(d.Current)
+			}
+			changes = append(changes, c)
+		}
+	}
+
+	addNodes(diff.NodeAdditions, "addition")
+	addNodes(diff.NodeModifications, "modification")
+	addNodes(diff.NodeDeletions, "deletion")
+	addServices(diff.ServiceAdditions, "addition")
+	addServices(diff.ServiceModifications, "modification")
+	addServices(diff.ServiceDeletions, "deletion")
+
+	for _, d := range diff.CheckDeletions {
+		c := targetChange{
+			Kind:     "check",
+			Target:   fmt.Sprintf("%s/%s", d.Node, d.CheckID),
+			Change:   "deletion",
+			Cascaded: d.Cascaded,
+			Line:     d.Line,
+		}
+		if d.Current != nil {
+			c.Current = catalogdiff.ToJSONValue(d.Current)
+		}
+		changes = append(changes, c)
+	}
+
+	return changes
+}
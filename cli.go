@@ -4,12 +4,82 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"strings"
+	"time"
+
+	"github.com/zinrai/consul-catalog-diff/catalogdiff"
 )
 
 // Config holds command-line configuration
 type Config struct {
+	File        string
+	ConsulAddr  string
+	Consistency string
+	MaxStaleLag time.Duration
+	Deadline    time.Duration
+	StateFile   string
+	Snapshot    string
+	SaveState   string
+	Record      string
+	Replay      string
+	Strict      bool
+	IgnoreFile  string
+	Selector    catalogdiff.Selector
+	FailOn      []FailTerm
+	Output      string
+	OutputFile  string
+	Template    string
+	Color       string
+	ConsulFlags
+	catalogdiff.DiffOptions
+}
+
+// ValidateConfig holds configuration for the validate subcommand
+type ValidateConfig struct {
 	File       string
 	ConsulAddr string
+	ConsulFlags
+}
+
+// ExportConfig holds configuration for the export subcommand
+type ExportConfig struct {
+	ConsulAddr string
+	OutputFile string
+	Selector   catalogdiff.Selector
+	ConsulFlags
+}
+
+// ConsulFlags holds the settings of Consul requests shared by the diff and
+// the subcommands
+type ConsulFlags struct {
+	Timeout time.Duration
+	Retry   catalogdiff.RetryPolicy
+}
+
+// register adds -timeout, -retries and -retry-max-wait to a flag set
+func (c *ConsulFlags) register(fs *flag.FlagSet) {
+	fs.DurationVar(&c.Timeout, "timeout", 30*time.Second, "Timeout of each Consul request (0 for none)")
+	fs.IntVar(&c.Retry.Retries, "retries", 3, "Retries of Consul requests failing with connection errors or 429, 500 and 503 responses")
+	fs.DurationVar(&c.Retry.MaxWait, "retry-max-wait", 10*time.Second, "Maximum wait between retries, including Retry-After")
+}
+
+// validate checks the values of the flags
+func (c ConsulFlags) validate() error {
+	if c.Timeout < 0 {
+		return fmt.Errorf("-timeout must not be negative")
+	}
+	if c.Retry.Retries < 0 || c.Retry.MaxWait <= 0 {
+		return fmt.Errorf("-retries must not be negative and -retry-max-wait must be positive")
+	}
+	return nil
+}
+
+// newConsulSource creates a ConsulSource for addr with the request settings
+func (c ConsulFlags) newConsulSource(addr string) *catalogdiff.ConsulSource {
+	src := catalogdiff.NewConsulSource(addr)
+	src.Client.Timeout = c.Timeout
+	src.Retry = c.Retry
+	return src
 }
 
 func parseConfig() Config {
@@ -17,6 +87,37 @@ func parseConfig() Config {
 
 	flag.StringVar(&config.File, "file", "", "JSON/NDJSON file containing expected operations (required)")
 	flag.StringVar(&config.ConsulAddr, "consul-addr", "http://127.0.0.1:8500", "Consul HTTP address")
+	flag.StringVar(&config.Consistency, "consistency", "default", "Consistency mode of catalog reads: stale, default or consistent")
+	flag.DurationVar(&config.MaxStaleLag, "max-stale-lag", 5*time.Second, "Warn when reads lag further behind the leader than this (0 to disable)")
+	config.ConsulFlags.register(flag.CommandLine)
+	flag.DurationVar(&config.Deadline, "deadline", 0, "Deadline for the whole run, across all Consul requests and retries (0 for none)")
+	flag.StringVar(&config.StateFile, "state-file", "", "Diff against a JSON state dump instead of querying Consul")
+	flag.StringVar(&config.Snapshot, "snapshot", "", "Diff against a snapshot archive saved by consul snapshot save instead of querying Consul")
+	flag.StringVar(&config.SaveState, "save-state", "", "Save the current state fetched for the diff as a JSON dump")
+	flag.StringVar(&config.Record, "record", "", "Save every Consul HTTP response to this directory")
+	flag.StringVar(&config.Replay, "replay", "", "Serve Consul HTTP responses recorded with -record from this directory")
+	flag.StringVar(&config.Output, "output", "text", "Output format: text, unified, junit, github, sarif or html")
+	flag.StringVar(&config.Template, "template", "", "Render the text report with this Go text/template file")
+	flag.StringVar(&config.OutputFile, "output-file", "", "Write the report to this file instead of stdout")
+	flag.StringVar(&config.Color, "color", "auto", "Color output: auto, always or never")
+	flag.BoolVar(&config.Strict, "strict", false, "Treat unrecognized operation types and fields as errors")
+	flag.Func("node", "Only diff nodes matching this glob or /regex/", patternFlag(&config.Selector.Node))
+	flag.Func("service", "Only diff services whose ID or name matches this glob or /regex/", patternFlag(&config.Selector.Service))
+	flag.Func("node-meta", "Only diff nodes whose payload Meta matches key=value (repeatable)", config.Selector.AddNodeMeta)
+	flag.Func("tag", "Only diff services with a tag matching this glob or /regex/", patternFlag(&config.Selector.Tag))
+	flag.Func("fail-on", "Change categories or thresholds that fail the run (repeatable)", func(value string) error {
+		terms, err := parseFailTerms(value)
+		if err != nil {
+			return err
+		}
+		config.FailOn = append(config.FailOn, terms...)
+		return nil
+	})
+	flag.StringVar(&config.IgnoreFile, "ignore-file", "", "File with rules for differences to ignore")
+	flag.Func("strict-fields", "Report values only present in Consul for these fields (all or comma-separated list)", func(value string) error {
+		config.StrictFields = splitList(value)
+		return nil
+	})
 
 	// Handle special flags before parsing
 	if handleSpecialFlags() {
@@ -32,9 +133,135 @@ func parseConfig() Config {
 		os.Exit(2)
 	}
 
+	switch config.Output {
+	case "text", "unified", "junit", "github", "sarif", "html":
+	default:
+		fmt.Fprintf(os.Stderr, "Error: unknown -output format %q\n\n", config.Output)
+		showUsage()
+		os.Exit(2)
+	}
+
+	if _, err := catalogdiff.ParseConsistency(config.Consistency); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n\n", err)
+		showUsage()
+		os.Exit(2)
+	}
+
+	if config.Deadline < 0 || config.MaxStaleLag < 0 {
+		fmt.Fprintf(os.Stderr, "Error: -deadline and -max-stale-lag must not be negative\n\n")
+		showUsage()
+		os.Exit(2)
+	}
+
+	if err := config.ConsulFlags.validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n\n", err)
+		showUsage()
+		os.Exit(2)
+	}
+
+	switch {
+	case config.Record != "" && config.Replay != "":
+		fmt.Fprintf(os.Stderr, "Error: -record cannot be combined with -replay\n\n")
+		showUsage()
+		os.Exit(2)
+	case config.StateFile != "" && config.Snapshot != "":
+		fmt.Fprintf(os.Stderr, "Error: -state-file cannot be combined with -snapshot\n\n")
+		showUsage()
+		os.Exit(2)
+	case (config.StateFile != "" || config.Snapshot != "") && (config.Record != "" || config.Replay != ""):
+		fmt.Fprintf(os.Stderr, "Error: -state-file and -snapshot cannot be combined with -record or -replay\n\n")
+		showUsage()
+		os.Exit(2)
+	}
+
+	if config.Template != "" && config.Output != "text" {
+		fmt.Fprintf(os.Stderr, "Error: -template cannot be combined with -output %s\n\n", config.Output)
+		showUsage()
+		os.Exit(2)
+	}
+
+	switch config.Color {
+	case "auto", "always", "never":
+	default:
+		fmt.Fprintf(os.Stderr, "Error: unknown -color mode %q\n\n", config.Color)
+		showUsage()
+		os.Exit(2)
+	}
+
+	return config
+}
+
+func parseValidateConfig(args []string) ValidateConfig {
+	var config ValidateConfig
+
+	fs := flag.NewFlagSet("validate", flag.ExitOnError)
+	fs.Usage = showUsage
+	fs.StringVar(&config.File, "file", "", "JSON/NDJSON file containing expected operations (required)")
+	fs.StringVar(&config.ConsulAddr, "consul-addr", "", "Consul HTTP address used to check referenced nodes")
+	config.ConsulFlags.register(fs)
+	fs.Parse(args)
+
+	if config.File == "" {
+		fmt.Fprintf(os.Stderr, "Error: -file flag is required\n\n")
+		showUsage()
+		os.Exit(2)
+	}
+
+	if err := config.ConsulFlags.validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n\n", err)
+		showUsage()
+		os.Exit(2)
+	}
+
 	return config
 }
 
+func parseExportConfig(args []string) ExportConfig {
+	var config ExportConfig
+
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	fs.Usage = showUsage
+	fs.StringVar(&config.ConsulAddr, "consul-addr", "http://127.0.0.1:8500", "Consul HTTP address")
+	fs.StringVar(&config.OutputFile, "output-file", "", "Write the operations to this file instead of stdout")
+	fs.Func("node", "Only export nodes matching this glob or /regex/", patternFlag(&config.Selector.Node))
+	fs.Func("service", "Only export services whose ID or name matches this glob or /regex/", patternFlag(&config.Selector.Service))
+	fs.Func("node-meta", "Only export nodes whose Meta matches key=value (repeatable)", config.Selector.AddNodeMeta)
+	fs.Func("tag", "Only export services with a tag matching this glob or /regex/", patternFlag(&config.Selector.Tag))
+	config.ConsulFlags.register(fs)
+	fs.Parse(args)
+
+	if err := config.ConsulFlags.validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n\n", err)
+		showUsage()
+		os.Exit(2)
+	}
+
+	return config
+}
+
+// patternFlag returns a flag handler that compiles a selector pattern
+func patternFlag(target **catalogdiff.Pattern) func(string) error {
+	return func(value string) error {
+		p, err := catalogdiff.CompilePattern(value)
+		if err != nil {
+			return err
+		}
+		*target = p
+		return nil
+	}
+}
+
+// splitList splits a comma-separated flag value, dropping empty items
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
 // handleSpecialFlags handles version and help flags
 func handleSpecialFlags() bool {
 	if len(os.Args) <= 1 {
@@ -57,20 +284,104 @@ func showUsage() {
 	fmt.Fprintf(os.Stderr, "%s - Detect differences between JSON operations and Consul Catalog\n\n", binaryName)
 	fmt.Fprintf(os.Stderr, "Version: %s\n\n", version)
 	fmt.Fprintf(os.Stderr, "Usage:\n")
-	fmt.Fprintf(os.Stderr, "  %s -file <path> [options]\n\n", binaryName)
+	fmt.Fprintf(os.Stderr, "  %s -file <path> [options]\n", binaryName)
+	fmt.Fprintf(os.Stderr, "  %s validate -file <path> [-consul-addr <url>] [request flags]\n", binaryName)
+	fmt.Fprintf(os.Stderr, "  %s export [-consul-addr <url>] [-output-file <path>] [selectors] [request flags]\n\n", binaryName)
+	fmt.Fprintf(os.Stderr, "Subcommands:\n")
+	fmt.Fprintf(os.Stderr, "  validate     Check the operations schema without diffing; only contacts\n")
+	fmt.Fprintf(os.Stderr, "               Consul to look up referenced nodes when -consul-addr is set\n")
+	fmt.Fprintf(os.Stderr, "  export       Write the registered nodes and services as NDJSON set\n")
+	fmt.Fprintf(os.Stderr, "               operations; accepts -node, -service, -node-meta and -tag\n")
+	fmt.Fprintf(os.Stderr, "  Both accept the request flags -timeout, -retries and -retry-max-wait.\n\n")
 	fmt.Fprintf(os.Stderr, "Required flags:\n")
 	fmt.Fprintf(os.Stderr, "  -file        Path to JSON/NDJSON file containing expected operations\n\n")
 	fmt.Fprintf(os.Stderr, "Optional flags:\n")
 	fmt.Fprintf(os.Stderr, "  -consul-addr Consul HTTP address (default: http://127.0.0.1:8500)\n")
+	fmt.Fprintf(os.Stderr, "  -consistency Consistency mode of catalog reads (default: default):\n")
+	fmt.Fprintf(os.Stderr, "                 stale       Any server answers; cheap but may lag behind\n")
+	fmt.Fprintf(os.Stderr, "                 default     The leader answers\n")
+	fmt.Fprintf(os.Stderr, "                 consistent  The leader verifies its leadership first\n")
+	fmt.Fprintf(os.Stderr, "  -max-stale-lag\n")
+	fmt.Fprintf(os.Stderr, "               Warn when reads lag further behind the leader, per\n")
+	fmt.Fprintf(os.Stderr, "               X-Consul-LastContact, 0 to disable (default: 5s)\n")
+	fmt.Fprintf(os.Stderr, "  -timeout     Timeout of each Consul request, 0 for none (default: 30s)\n")
+	fmt.Fprintf(os.Stderr, "  -deadline    Deadline for the whole run across all Consul requests and\n")
+	fmt.Fprintf(os.Stderr, "               retries, 0 for none (default: 0)\n")
+	fmt.Fprintf(os.Stderr, "  -retries     Retries of Consul requests failing with connection errors or\n")
+	fmt.Fprintf(os.Stderr, "               429, 500 or 503 responses, with exponential backoff (default: 3)\n")
+	fmt.Fprintf(os.Stderr, "  -retry-max-wait\n")
+	fmt.Fprintf(os.Stderr, "               Maximum wait between retries, also capping Retry-After\n")
+	fmt.Fprintf(os.Stderr, "               (default: 10s)\n")
+	fmt.Fprintf(os.Stderr, "  -state-file  Diff against a JSON state dump (from -save-state) instead of\n")
+	fmt.Fprintf(os.Stderr, "               querying Consul\n")
+	fmt.Fprintf(os.Stderr, "  -snapshot    Diff against a snapshot archive saved by 'consul snapshot save'\n")
+	fmt.Fprintf(os.Stderr, "               instead of querying Consul\n")
+	fmt.Fprintf(os.Stderr, "  -save-state  Save the current state fetched for the diff as a JSON dump\n")
+	fmt.Fprintf(os.Stderr, "  -record      Save every Consul HTTP response to a directory\n")
+	fmt.Fprintf(os.Stderr, "  -replay      Serve Consul HTTP responses recorded with -record from a\n")
+	fmt.Fprintf(os.Stderr, "               directory instead of querying Consul\n")
+	fmt.Fprintf(os.Stderr, "  -output      Output format (default: text):\n")
+	fmt.Fprintf(os.Stderr, "                 text     Human-readable report\n")
+	fmt.Fprintf(os.Stderr, "                 unified  Unified diff of current vs expected JSON per target\n")
+	fmt.Fprintf(os.Stderr, "                 junit    JUnit XML, one test case per target, failing on drift\n")
+	fmt.Fprintf(os.Stderr, "                 github   GitHub Actions annotations on the payload lines, plus a\n")
+	fmt.Fprintf(os.Stderr, "                          job summary written to $GITHUB_STEP_SUMMARY\n")
+	fmt.Fprintf(os.Stderr, "                 sarif    SARIF 2.1.0 log for code scanning dashboards\n")
+	fmt.Fprintf(os.Stderr, "                 html     Self-contained HTML page for reviews in a browser\n")
+	fmt.Fprintf(os.Stderr, "  -output-file Write the report to this file instead of stdout\n")
+	fmt.Fprintf(os.Stderr, "  -template    Render the report with a Go text/template file instead of\n")
+	fmt.Fprintf(os.Stderr, "               the built-in text report (see README for helper functions)\n")
+	fmt.Fprintf(os.Stderr, "  -color       Color output: auto, always or never (default: auto, which\n")
+	fmt.Fprintf(os.Stderr, "               colors when stdout is a terminal and NO_COLOR is not set)\n")
+	fmt.Fprintf(os.Stderr, "  -strict      Fail on unrecognized operation types and fields\n")
+	fmt.Fprintf(os.Stderr, "  -node        Only diff nodes matching a glob or /regex/\n")
+	fmt.Fprintf(os.Stderr, "  -service     Only diff services whose ID or name matches a glob or /regex/\n")
+	fmt.Fprintf(os.Stderr, "  -node-meta   Only diff nodes whose payload Meta matches key=value (repeatable)\n")
+	fmt.Fprintf(os.Stderr, "  -tag         Only diff services with a tag matching a glob or /regex/\n")
+	fmt.Fprintf(os.Stderr, "  -fail-on     Comma-separated categories that fail the run, optionally with a\n")
+	fmt.Fprintf(os.Stderr, "               threshold (e.g. delete,modifications>5); repeatable. Categories:\n")
+	fmt.Fprintf(os.Stderr, "               any, add, modify, delete, orphan, node-add, node-modify,\n")
+	fmt.Fprintf(os.Stderr, "               node-delete, service-add, service-modify, service-delete,\n")
+	fmt.Fprintf(os.Stderr, "               check-delete\n")
+	fmt.Fprintf(os.Stderr, "  -ignore-file File with rules for differences to ignore, one per line:\n")
+	fmt.Fprintf(os.Stderr, "               node:web-* Meta.last-deploy, service:*/nginx Tags, service:*/consul\n")
+	fmt.Fprintf(os.Stderr, "  -strict-fields all|FIELD[,FIELD...]\n")
+	fmt.Fprintf(os.Stderr, "               Treat the payload as the complete definition of these fields\n")
+	fmt.Fprintf(os.Stderr, "               and report Meta keys, tags and other values only set in Consul\n")
 	fmt.Fprintf(os.Stderr, "  -version     Show version\n")
 	fmt.Fprintf(os.Stderr, "  -help        Show this help message\n")
 	fmt.Fprintf(os.Stderr, "\nExamples:\n")
 	fmt.Fprintf(os.Stderr, "  # Check differences from file\n")
 	fmt.Fprintf(os.Stderr, "  %s -file operations.json -consul-addr http://consul:8500\n\n", binaryName)
+	fmt.Fprintf(os.Stderr, "  # Only diff nginx services on web nodes\n")
+	fmt.Fprintf(os.Stderr, "  %s -file operations.json -node 'web-*' -service nginx\n\n", binaryName)
+	fmt.Fprintf(os.Stderr, "  # Review drift as a unified diff\n")
+	fmt.Fprintf(os.Stderr, "  %s -file operations.json -output unified | delta\n\n", binaryName)
+	fmt.Fprintf(os.Stderr, "  # Report drift as test failures in CI\n")
+	fmt.Fprintf(os.Stderr, "  %s -file operations.json -output junit > catalog-diff.xml\n\n", binaryName)
+	fmt.Fprintf(os.Stderr, "  # Write an HTML report for an audit review\n")
+	fmt.Fprintf(os.Stderr, "  %s -file operations.json -output html -output-file report.html\n\n", binaryName)
+	fmt.Fprintf(os.Stderr, "  # Capture the state in CI and diff against it later\n")
+	fmt.Fprintf(os.Stderr, "  %s -file operations.json -save-state state.json\n", binaryName)
+	fmt.Fprintf(os.Stderr, "  %s -file operations.json -state-file state.json\n\n", binaryName)
+	fmt.Fprintf(os.Stderr, "  # Diff against a backup while Consul is unreachable\n")
+	fmt.Fprintf(os.Stderr, "  %s -file operations.json -snapshot backup.snap\n\n", binaryName)
+	fmt.Fprintf(os.Stderr, "  # Record the Consul responses of a CI run and reproduce it locally\n")
+	fmt.Fprintf(os.Stderr, "  %s -file operations.json -record ci-responses\n", binaryName)
+	fmt.Fprintf(os.Stderr, "  %s -file operations.json -replay ci-responses\n\n", binaryName)
+	fmt.Fprintf(os.Stderr, "  # Validate a payload offline\n")
+	fmt.Fprintf(os.Stderr, "  %s validate -file operations.json\n\n", binaryName)
+	fmt.Fprintf(os.Stderr, "  # Bootstrap a payload from the web nodes of an existing cluster\n")
+	fmt.Fprintf(os.Stderr, "  %s export -node 'web-*' -output-file operations.ndjson\n\n", binaryName)
 	fmt.Fprintf(os.Stderr, "  # Use process substitution\n")
 	fmt.Fprintf(os.Stderr, "  %s -file <(consul-catalog-sync -payload) -consul-addr http://consul:8500\n\n", binaryName)
 	fmt.Fprintf(os.Stderr, "Exit codes:\n")
-	fmt.Fprintf(os.Stderr, "  0 - No differences found\n")
-	fmt.Fprintf(os.Stderr, "  1 - Differences found\n")
+	fmt.Fprintf(os.Stderr, "  0 - No differences found (or none matching -fail-on)\n")
+	fmt.Fprintf(os.Stderr, "  1 - Differences found (without -fail-on, or matching -fail-on any)\n")
 	fmt.Fprintf(os.Stderr, "  2 - Error occurred\n")
+	fmt.Fprintf(os.Stderr, "  With -fail-on, the most severe matching category sets the exit code:\n")
+	fmt.Fprintf(os.Stderr, "  3 - Additions\n")
+	fmt.Fprintf(os.Stderr, "  4 - Modifications\n")
+	fmt.Fprintf(os.Stderr, "  5 - Orphan services (added to a node neither registered nor in the payload)\n")
+	fmt.Fprintf(os.Stderr, "  6 - Deletions\n")
 }
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// ANSI escape sequences used in colored output
+const (
+	colorReset  = "\033[0m"
+	colorRed    = "\033[31m"
+	colorGreen  = "\033[32m"
+	colorYellow = "\033[33m"
+	colorCyan   = "\033[36m"
+	colorBold   = "\033[1m"
+	colorDim    = "\033[2m"
+)
+
+// reportWriter writes reports, optionally colored with ANSI escape sequences
+type reportWriter struct {
+	w     io.Writer
+	color bool
+}
+
+// newReportWriter creates a reportWriter
+func newReportWriter(w io.Writer, color bool) *reportWriter {
+	return &reportWriter{w: w, color: color}
+}
+
+func (r *reportWriter) printf(format string, args ...interface{}) {
+	fmt.Fprintf(r.w, format, args...)
+}
+
+func (r *reportWriter) println(args ...interface{}) {
+	fmt.Fprintln(r.w, args...)
+}
+
+func (r *reportWriter) print(args ...interface{}) {
+	fmt.Fprint(r.w, args...)
+}
+
+// colorize wraps s in an escape sequence when color is enabled
+func (r *reportWriter) colorize(color, s string) string {
+	if !r.color || s == "" {
+		return s
+	}
+	return color + s + colorReset
+}
+
+// useColor decides whether to color output written to f for a -color mode.
+// In auto mode, color is used when f is a terminal and NO_COLOR is not set.
+func useColor(mode string, f *os.File) bool {
+	switch mode {
+	case "always":
+		return true
+	case "never":
+		return false
+	default:
+		if os.Getenv("NO_COLOR") != "" {
+			return false
+		}
+		return isTerminal(f)
+	}
+}
+
+// isTerminal checks if a file is a character device such as a terminal
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
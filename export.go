@@ -0,0 +1,49 @@
+package main
+
+import (
+	"log"
+	"os"
+
+	"github.com/zinrai/consul-catalog-diff/catalogdiff"
+)
+
+// runExport implements the export subcommand and returns the exit code
+func runExport(args []string) int {
+	config := parseExportConfig(args)
+	setupLogging(Config{})
+
+	ctx, stop := interruptContext()
+	defer stop()
+
+	src := config.newConsulSource(config.ConsulAddr)
+	src.Logger = log.Default()
+	operations, err := src.Export(ctx, config.Selector)
+	if err != nil {
+		log.Printf("[ERROR] Failed to export catalog: %v", err)
+		return 2
+	}
+
+	dest := os.Stdout
+	if config.OutputFile != "" {
+		f, err := os.Create(config.OutputFile)
+		if err != nil {
+			log.Printf("[ERROR] Failed to create output file: %v", err)
+			return 2
+		}
+		dest = f
+	}
+
+	if err := catalogdiff.WriteNDJSON(dest, operations); err != nil {
+		log.Printf("[ERROR] Failed to write operations: %v", err)
+		return 2
+	}
+
+	if config.OutputFile != "" {
+		if err := dest.Close(); err != nil {
+			log.Printf("[ERROR] Failed to write operations: %v", err)
+			return 2
+		}
+		log.Printf("[INFO] Wrote %d operations to %s", len(operations), config.OutputFile)
+	}
+	return 0
+}
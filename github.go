@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/zinrai/consul-catalog-diff/catalogdiff"
+)
+
+// githubChangeTitles maps change types to annotation titles
+var githubChangeTitles = map[string]string{
+	"addition":     "Catalog addition",
+	"modification": "Catalog drift",
+	"deletion":     "Catalog deletion",
+}
+
+// outputGitHub outputs a GitHub Actions warning annotation for each change,
+// pointing at the line of the operation in file, and writes a job summary
+// to $GITHUB_STEP_SUMMARY when it is set
+func (r *reportWriter) outputGitHub(file string, diff *catalogdiff.DiffResult) error {
+	if diff.Reads != nil {
+		r.printf("::notice title=Consul reads::%s\n", escapeGitHubData(readsSummary(diff.Reads)))
+		for _, w := range readsWarnings(diff.Reads) {
+			r.printf("::warning title=Consul reads::%s\n", escapeGitHubData(w))
+		}
+	}
+
+	changes := collectChanges(diff)
+
+	for _, c := range changes {
+		var props []string
+		if file != "" && c.Line > 0 {
+			props = append(props, "file="+escapeGitHubProperty(file), fmt.Sprintf("line=%d", c.Line))
+		}
+		props = append(props, "title="+escapeGitHubProperty(githubChangeTitles[c.Change]+": "+c.Name()))
+
+		message := c.Summary()
+		if details := c.Details(); details != "" {
+			message += "\n" + details
+		}
+		r.printf("::warning %s::%s\n", strings.Join(props, ","), escapeGitHubData(message))
+	}
+
+	if len(changes) == 0 {
+		r.println("::notice title=Consul Catalog Diff::No differences found")
+	}
+
+	summaryPath := os.Getenv("GITHUB_STEP_SUMMARY")
+	if summaryPath == "" {
+		log.Printf("[INFO] GITHUB_STEP_SUMMARY is not set, skipping job summary")
+		return nil
+	}
+
+	// Other steps may have written to the summary already, so append
+	summary, err := os.OpenFile(summaryPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open job summary: %w", err)
+	}
+	defer summary.Close()
+
+	if err := writeGitHubSummary(summary, file, diff); err != nil {
+		return fmt.Errorf("failed to write job summary: %w", err)
+	}
+	return nil
+}
+
+// writeGitHubSummary writes a Markdown summary of the differences
+func writeGitHubSummary(w io.Writer, file string, diff *catalogdiff.DiffResult) error {
+	var b strings.Builder
+
+	b.WriteString("## Consul Catalog Diff\n\n")
+	if diff.Reads != nil {
+		fmt.Fprintf(&b, "%s\n\n", readsSummary(diff.Reads))
+		for _, w := range readsWarnings(diff.Reads) {
+			fmt.Fprintf(&b, "**Warning:** %s\n\n", w)
+		}
+	}
+	if !diff.HasChanges() {
+		b.WriteString("No differences found.\n")
+	} else {
+		fmt.Fprintf(&b, "**Total changes: %d**\n\n", diff.TotalChanges())
+		b.WriteString("| Kind | Additions | Modifications | Deletions |\n")
+		b.WriteString("| --- | ---: | ---: | ---: |\n")
+		fmt.Fprintf(&b, "| Node | %d | %d | %d |\n", len(diff.NodeAdditions), len(diff.NodeModifications), len(diff.NodeDeletions))
+		fmt.Fprintf(&b, "| Service | %d | %d | %d |\n", len(diff.ServiceAdditions), len(diff.ServiceModifications), len(diff.ServiceDeletions))
+		fmt.Fprintf(&b, "| Check | 0 | 0 | %d |\n\n", len(diff.CheckDeletions))
+
+		b.WriteString("| Change | Target | Location | Details |\n")
+		b.WriteString("| --- | --- | --- | --- |\n")
+		for _, c := range collectChanges(diff) {
+			location := ""
+			if file != "" && c.Line > 0 {
+				location = fmt.Sprintf("`%s:%d`", file, c.Line)
+			}
+			details := c.Summary()
+			if c.Details() != "" {
+				details = strings.ReplaceAll(c.Details(), "\n", "<br>")
+			}
+			fmt.Fprintf(&b, "| %s | `%s` | %s | %s |\n", c.Change, c.Name(), location, escapeMarkdownCell(details))
+		}
+	}
+
+	if len(diff.Ignored) > 0 {
+		fmt.Fprintf(&b, "\n%d difference(s) ignored by ignore rules.\n", len(diff.Ignored))
+	}
+	if len(diff.Conflicts) > 0 {
+		fmt.Fprintf(&b, "\n%d target(s) with duplicate or conflicting operations.\n", len(diff.Conflicts))
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// escapeGitHubData escapes a workflow command message
+func escapeGitHubData(s string) string {
+	s = strings.ReplaceAll(s, "%", "%25")
+	s = strings.ReplaceAll(s, "\r", "%0D")
+	return strings.ReplaceAll(s, "\n", "%0A")
+}
+
+// escapeGitHubProperty escapes a workflow command property value
+func escapeGitHubProperty(s string) string {
+	s = escapeGitHubData(s)
+	s = strings.ReplaceAll(s, ":", "%3A")
+	return strings.ReplaceAll(s, ",", "%2C")
+}
+
+// escapeMarkdownCell escapes pipes, which would end a table cell
+func escapeMarkdownCell(s string) string {
+	return strings.ReplaceAll(s, "|", "\\|")
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	_ "embed"
+	"fmt"
+	"html/template"
+	"time"
+
+	"github.com/zinrai/consul-catalog-diff/catalogdiff"
+)
+
+//go:embed templates/report.html
+var htmlReportTemplate string
+
+// htmlReport is the data rendered by the HTML report template
+type htmlReport struct {
+	Title         string
+	Generated     string
+	Total         int
+	Additions     int
+	Modifications int
+	Deletions     int
+	Sections      []htmlSection
+	Ignored       []catalogdiff.IgnoredDiff
+	Conflicts     []catalogdiff.OperationConflict
+	Reads         *catalogdiff.ReadStats
+}
+
+// htmlSection is a table of changes of one kind
+type htmlSection struct {
+	Kind    string
+	Title   string
+	Changes []htmlChange
+}
+
+// htmlChange is a row of a section table
+type htmlChange struct {
+	targetChange
+	Summary string
+	Fields  []htmlField
+	Rows    []sideBySideRow
+}
+
+// htmlField is a field difference with formatted values
+type htmlField struct {
+	Field    string
+	Current  string
+	Expected string
+}
+
+// sideBySideRow is a line of the current and expected JSON documents
+type sideBySideRow struct {
+	Current         string
+	Expected        string
+	CurrentChanged  bool
+	ExpectedChanged bool
+}
+
+// outputHTML outputs a self-contained HTML page for reviewing the
+// differences in a browser, with all CSS and JavaScript inlined
+func (r *reportWriter) outputHTML(diff *catalogdiff.DiffResult, generated time.Time) error {
+	tmpl, err := template.New("report").Parse(htmlReportTemplate)
+	if err != nil {
+		return fmt.Errorf("failed to parse HTML template: %w", err)
+	}
+
+	report := htmlReport{
+		Title:     "Consul Catalog Diff Report",
+		Generated: generated.UTC().Format(time.RFC1123),
+		Total:     diff.TotalChanges(),
+		Ignored:   diff.Ignored,
+		Conflicts: diff.Conflicts,
+		Reads:     diff.Reads,
+		Sections: []htmlSection{
+			{Kind: "node", Title: "Nodes"},
+			{Kind: "service", Title: "Services"},
+			{Kind: "check", Title: "Checks"},
+		},
+	}
+
+	for _, c := range collectChanges(diff) {
+		switch c.Change {
+		case "addition":
+			report.Additions++
+		case "modification":
+			report.Modifications++
+		case "deletion":
+			report.Deletions++
+		}
+
+		for i := range report.Sections {
+			if report.Sections[i].Kind == c.Kind {
+				report.Sections[i].Changes = append(report.Sections[i].Changes, newHTMLChange(c))
+			}
+		}
+	}
+
+	if err := tmpl.Execute(r.w, report); err != nil {
+		return fmt.Errorf("failed to render HTML report: %w", err)
+	}
+	return nil
+}
+
+// newHTMLChange prepares a change for the HTML report. The current
+// object is built from the field differences, as in the unified output.
+func newHTMLChange(c targetChange) htmlChange {
+	hc := htmlChange{targetChange: c, Summary: c.Summary()}
+
+	for _, f := range c.Fields {
+		hc.Fields = append(hc.Fields, htmlField{
+			Field:    f.Field,
+			Current:  formatFieldValue(f.Current),
+			Expected: formatFieldValue(f.Expected),
+		})
+	}
+
+	var current, expected []string
+	switch c.Change {
+	case "addition":
+		expected = jsonLines(c.Expected)
+	case "modification":
+		current = jsonLines(currentDocument(c.Expected, c.Fields))
+		expected = jsonLines(c.Expected)
+	case "deletion":
+		current = jsonLines(deletedDocument(c.Current))
+	}
+	hc.Rows = sideBySide(current, expected)
+
+	return hc
+}
+
+// sideBySide aligns two documents line by line, pairing removed lines with
+// the added lines that follow them
+func sideBySide(current, expected []string) []sideBySideRow {
+	edits := diffLines(current, expected)
+
+	var rows []sideBySideRow
+	for i := 0; i < len(edits); {
+		if edits[i].op == ' ' {
+			rows = append(rows, sideBySideRow{Current: edits[i].text, Expected: edits[i].text})
+			i++
+			continue
+		}
+
+		var removed, added []string
+		for ; i < len(edits) && edits[i].op == '-'; i++ {
+			removed = append(removed, edits[i].text)
+		}
+		for ; i < len(edits) && edits[i].op == '+'; i++ {
+			added = append(added, edits[i].text)
+		}
+
+		for j := 0; j < max(len(removed), len(added)); j++ {
+			var row sideBySideRow
+			if j < len(removed) {
+				row.Current, row.CurrentChanged = removed[j], true
+			}
+			if j < len(added) {
+				row.Expected, row.ExpectedChanged = added[j], true
+			}
+			rows = append(rows, row)
+		}
+	}
+
+	return rows
+}
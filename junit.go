@@ -0,0 +1,170 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+
+	"github.com/zinrai/consul-catalog-diff/catalogdiff"
+)
+
+// junitTestSuites is the root element of a JUnit XML report
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+// junitTestSuite groups the test cases of one kind of target
+type junitTestSuite struct {
+	Name       string           `xml:"name,attr"`
+	Tests      int              `xml:"tests,attr"`
+	Failures   int              `xml:"failures,attr"`
+	Skipped    int              `xml:"skipped,attr"`
+	Properties *junitProperties `xml:"properties,omitempty"`
+	Cases      []junitTestCase  `xml:"testcase"`
+}
+
+// junitProperties holds the properties of a test suite
+type junitProperties struct {
+	Properties []junitProperty `xml:"property"`
+}
+
+// junitProperty describes the run, such as the consistency of the reads
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+// junitTestCase represents one target, failing if it drifted
+type junitTestCase struct {
+	ClassName string        `xml:"classname,attr"`
+	Name      string        `xml:"name,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Skipped   *junitSkipped `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+// junitFailure describes the drift of a target
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// junitSkipped marks a target that is not compared
+type junitSkipped struct {
+	Message string `xml:"message,attr"`
+}
+
+// junitSuiteOrder lists the test suites in output order
+var junitSuiteOrder = []string{"node", "service", "check"}
+
+// outputJUnit outputs a JUnit XML report with one test case per target.
+// Targets in sync pass; targets with changes fail with their field diffs.
+// Check operations are not compared, so their targets are skipped unless a
+// cascaded deletion fails them. Targets only affected implicitly, such as
+// cascaded deletions, are added as failing test cases after those from the
+// payload.
+func (r *reportWriter) outputJUnit(operations []catalogdiff.Operation, diff *catalogdiff.DiffResult) error {
+	changes := make(map[string]targetChange)
+	var changeOrder []string
+	for _, c := range collectChanges(diff) {
+		changes[c.Name()] = c
+		changeOrder = append(changeOrder, c.Name())
+	}
+
+	ignored := make(map[string][]catalogdiff.IgnoredDiff)
+	for _, ig := range diff.Ignored {
+		name := ig.Kind + "/" + ig.Target
+		ignored[name] = append(ignored[name], ig)
+	}
+
+	suites := make(map[string]*junitTestSuite)
+	for _, kind := range junitSuiteOrder {
+		suites[kind] = &junitTestSuite{Name: kind}
+	}
+
+	seen := make(map[string]bool)
+	addCase := func(kind, name string) {
+		if seen[name] {
+			return
+		}
+		seen[name] = true
+
+		tc := junitTestCase{ClassName: kind, Name: name}
+		if c, ok := changes[name]; ok {
+			tc.Failure = &junitFailure{
+				Message: c.Summary(),
+				Type:    c.Change,
+				Text:    c.Details(),
+			}
+		} else if kind == "check" {
+			tc.Skipped = &junitSkipped{Message: "check operations are not compared"}
+		}
+		for _, ig := range ignored[name] {
+			tc.SystemOut += fmt.Sprintf("ignored %s by rule %q\n", describeIgnored(ig), ig.Rule.String())
+		}
+
+		suite := suites[kind]
+		suite.Cases = append(suite.Cases, tc)
+		suite.Tests++
+		if tc.Failure != nil {
+			suite.Failures++
+		}
+		if tc.Skipped != nil {
+			suite.Skipped++
+		}
+	}
+
+	for _, op := range operations {
+		kind, target := catalogdiff.OperationTarget(op)
+		if kind != "" {
+			addCase(kind, kind+"/"+target)
+		}
+	}
+	for _, name := range changeOrder {
+		addCase(changes[name].Kind, name)
+	}
+
+	// JUnit has no properties on the root, so every suite gets them
+	var props *junitProperties
+	if diff.Reads != nil {
+		props = &junitProperties{Properties: []junitProperty{{Name: "consistency", Value: readsSummary(diff.Reads)}}}
+		for _, w := range readsWarnings(diff.Reads) {
+			props.Properties = append(props.Properties, junitProperty{Name: "warning", Value: w})
+		}
+	}
+
+	report := junitTestSuites{Name: binaryName}
+	for _, kind := range junitSuiteOrder {
+		suite := suites[kind]
+		if suite.Tests == 0 {
+			continue
+		}
+		suite.Properties = props
+		report.Suites = append(report.Suites, *suite)
+		report.Tests += suite.Tests
+		report.Failures += suite.Failures
+		report.Skipped += suite.Skipped
+	}
+
+	data, err := xml.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode JUnit report: %w", err)
+	}
+
+	r.print(xml.Header)
+	r.println(string(data))
+	return nil
+}
+
+// describeIgnored returns a short description of an ignored difference
+func describeIgnored(ig catalogdiff.IgnoredDiff) string {
+	if ig.Field == "" {
+		return ig.Change
+	}
+	return fmt.Sprintf("%s of %s", ig.Change, ig.Field)
+}
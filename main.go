@@ -1,8 +1,15 @@
 package main
 
 import (
+	"context"
 	"log"
 	"os"
+	"os/signal"
+	"syscall"
+	"text/template"
+	"time"
+
+	"github.com/zinrai/consul-catalog-diff/catalogdiff"
 )
 
 var (
@@ -11,35 +18,182 @@ var (
 )
 
 func main() {
+	// Dispatch subcommands
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "validate":
+			os.Exit(runValidate(os.Args[2:]))
+		case "export":
+			os.Exit(runExport(os.Args[2:]))
+		}
+	}
+
 	// Parse command line arguments
 	config := parseConfig()
 	setupLogging(config)
 
+	// Cancel in-flight requests on Ctrl-C and when the deadline expires
+	ctx, stop := interruptContext()
+	defer stop()
+	if config.Deadline > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, config.Deadline)
+		defer cancel()
+	}
+
+	// Load ignore rules
+	if config.IgnoreFile != "" {
+		rules, err := catalogdiff.LoadIgnoreRules(config.IgnoreFile)
+		if err != nil {
+			fatalf("[ERROR] Failed to load ignore rules: %v", err)
+		}
+		config.Ignore = rules
+	}
+
+	// Load the report template
+	var reportTemplate *template.Template
+	if config.Template != "" {
+		tmpl, err := loadReportTemplate(config.Template)
+		if err != nil {
+			fatalf("[ERROR] Failed to load template: %v", err)
+		}
+		reportTemplate = tmpl
+	}
+
 	// Load and parse input file
-	operations, err := loadOperations(config.File)
+	operations, err := catalogdiff.LoadOperations(config.File, catalogdiff.LoadOptions{Strict: config.Strict, Logger: log.Default()})
 	if err != nil {
-		log.Fatalf("[ERROR] Failed to load operations: %v", err)
+		fatalf("[ERROR] Failed to load operations: %v", err)
+	}
+
+	// Restrict operations to the selected targets
+	if !config.Selector.IsEmpty() {
+		selected := catalogdiff.SelectOperations(operations, config.Selector)
+		log.Printf("[INFO] Selected %d of %d operations", len(selected), len(operations))
+		operations = selected
+	}
+
+	// Fetch current state from Consul, a state file or a snapshot
+	consul := config.newConsulSource(config.ConsulAddr)
+	consul.Consistency = config.Consistency
+	consul.MaxStaleLag = config.MaxStaleLag
+	consul.Logger = log.Default()
+	switch {
+	case config.Record != "":
+		consul.Client.Transport = &catalogdiff.RecordingTransport{Dir: config.Record}
+	case config.Replay != "":
+		consul.Client.Transport = &catalogdiff.ReplayTransport{Dir: config.Replay}
+	}
+
+	var src catalogdiff.StateSource = consul
+	switch {
+	case config.StateFile != "":
+		src = &catalogdiff.FileSource{Path: config.StateFile}
+	case config.Snapshot != "":
+		src = &catalogdiff.SnapshotSource{Path: config.Snapshot, Logger: log.Default()}
 	}
 
-	// Fetch current state from Consul
-	currentState, err := fetchConsulState(config.ConsulAddr, operations)
+	// Calculate differences, saving the fetched state on the way
+	fetched := false
+	config.DiffOptions.Logger = log.Default()
+	config.DiffOptions.StateFetched = func(state *catalogdiff.ConsulState) error {
+		fetched = true
+		if config.Record != "" {
+			log.Printf("[INFO] Recorded Consul responses to %s", config.Record)
+		}
+		if config.SaveState != "" {
+			if err := catalogdiff.SaveState(config.SaveState, state); err != nil {
+				return err
+			}
+			log.Printf("[INFO] Saved current state to %s", config.SaveState)
+		}
+		return nil
+	}
+
+	diff, err := catalogdiff.Diff(ctx, operations, src, config.DiffOptions)
 	if err != nil {
-		log.Fatalf("[ERROR] Failed to fetch Consul state: %v", err)
+		if !fetched {
+			exitIfDone(ctx, config.Deadline, "the current state was fetched", err)
+		}
+		exitIfDone(ctx, config.Deadline, "the differences were calculated", err)
+		fatalf("[ERROR] %v", err)
+	}
+	if reads := diff.Reads; reads != nil {
+		if reads.Lagging {
+			log.Printf("[WARN] Consul reads lag %s behind the leader, more than %s", reads.MaxLastContact, reads.MaxStaleLag)
+		}
+		if reads.NoKnownLeader {
+			log.Printf("[WARN] Consul reported no known leader while reading the catalog")
+		}
 	}
 
-	// Calculate differences
-	diff := calculateDiff(operations, currentState)
+	// Output results, unless the run was interrupted in the meantime
+	exitIfDone(ctx, config.Deadline, "the report was written", ctx.Err())
+	dest := os.Stdout
+	if config.OutputFile != "" {
+		f, err := os.Create(config.OutputFile)
+		if err != nil {
+			fatalf("[ERROR] Failed to create output file: %v", err)
+		}
+		dest = f
+	}
 
-	// Output results
-	outputDiff(diff)
+	out := newReportWriter(dest, useColor(config.Color, dest))
+	switch config.Output {
+	case "unified":
+		out.outputUnified(diff)
+	case "junit":
+		err = out.outputJUnit(operations, diff)
+	case "github":
+		err = out.outputGitHub(config.File, diff)
+	case "sarif":
+		err = out.outputSARIF(config.File, diff)
+	case "html":
+		err = out.outputHTML(diff, time.Now())
+	default:
+		if reportTemplate != nil {
+			err = out.outputTemplate(reportTemplate, diff)
+		} else {
+			err = out.outputDiff(diff)
+		}
+	}
+	if err != nil {
+		fatalf("[ERROR] %v", err)
+	}
 
-	// Set exit code based on differences
-	if diff.HasChanges() {
-		os.Exit(1) // Differences found
+	if config.OutputFile != "" {
+		if err := dest.Close(); err != nil {
+			fatalf("[ERROR] Failed to write output file: %v", err)
+		}
+		log.Printf("[INFO] Wrote report to %s", config.OutputFile)
 	}
-	os.Exit(0) // No differences
+
+	// Set exit code based on differences and the -fail-on policy
+	os.Exit(exitCodeFor(diff, config.FailOn))
 }
 
 func setupLogging(config Config) {
 	log.SetFlags(0)
 }
+
+// interruptContext returns a context canceled on Ctrl-C or SIGTERM
+func interruptContext() (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+}
+
+// exitIfDone exits with 2 if ctx was canceled or its deadline exceeded
+// before the given step completed
+func exitIfDone(ctx context.Context, deadline time.Duration, step string, err error) {
+	switch ctx.Err() {
+	case context.Canceled:
+		fatalf("[ERROR] Interrupted before %s, no report written: %v", step, err)
+	case context.DeadlineExceeded:
+		fatalf("[ERROR] Deadline of %s exceeded before %s, no report written: %v", deadline, step, err)
+	}
+}
+
+// fatalf logs an error and exits with the error exit code
+func fatalf(format string, v ...interface{}) {
+	log.Printf(format, v...)
+	os.Exit(2)
+}
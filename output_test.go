@@ -0,0 +1,592 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zinrai/consul-catalog-diff/catalogdiff"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+// goldenDiff returns a DiffResult exercising every section of the reports
+func goldenDiff() *catalogdiff.DiffResult {
+	return &catalogdiff.DiffResult{
+		NodeAdditions: []catalogdiff.NodeDiff{{
+			Node: "web-003",
+			Expected: map[string]interface{}{
+				"Node":       "web-003",
+				"Address":    "10.0.0.3",
+				"Datacenter": "dc1",
+				"Meta":       map[string]interface{}{"type": "web"},
+			},
+			Line: 4,
+		}, {
+			// Values that are set but empty are still shown
+			Node:     "web-004",
+			Expected: map[string]interface{}{"Node": "web-004", "Address": ""},
+			Line:     9,
+		}},
+		NodeModifications: []catalogdiff.NodeDiff{{
+			Node:     "web-001",
+			Expected: map[string]interface{}{"Node": "web-001", "Address": "10.0.0.100", "Meta": map[string]interface{}{"location": "rack-2"}},
+			Current:  &catalogdiff.ConsulNode{Node: "web-001", Address: "10.0.0.1", Meta: map[string]string{"location": "rack-1"}},
+			Fields: []catalogdiff.FieldDiff{
+				{Field: "Address", Expected: "10.0.0.100", Current: "10.0.0.1"},
+				{Field: "Meta.location", Expected: "rack-2", Current: "rack-1"},
+			},
+			Line: 1,
+		}},
+		NodeDeletions: []catalogdiff.NodeDiff{{
+			Node:    "web-009",
+			Current: &catalogdiff.ConsulNode{Node: "web-009", Address: "10.0.0.9"},
+			Line:    7,
+		}},
+		ServiceAdditions: []catalogdiff.ServiceDiff{{
+			Node:      "web-003",
+			ServiceID: "nginx",
+			Expected: map[string]interface{}{
+				"ID":      "nginx",
+				"Service": "nginx",
+				"Port":    float64(80),
+				"Tags":    []interface{}{"web", "primary"},
+			},
+			Line: 6,
+		}, {
+			Node:      "web-004",
+			ServiceID: "metrics",
+			Expected: map[string]interface{}{
+				"ID":      "metrics",
+				"Service": "",
+				"Port":    float64(0),
+				"Tags":    nil,
+			},
+			Line: 10,
+		}},
+		ServiceModifications: []catalogdiff.ServiceDiff{{
+			Node:      "web-001",
+			ServiceID: "nginx",
+			Expected:  map[string]interface{}{"ID": "nginx", "Port": float64(8080)},
+			Current:   &catalogdiff.ConsulService{ID: "nginx", Service: "nginx", Port: 80},
+			Fields:    []catalogdiff.FieldDiff{{Field: "Port", Expected: 8080, Current: 80}},
+			Line:      5,
+		}},
+		ServiceDeletions: []catalogdiff.ServiceDiff{{
+			Node:      "web-009",
+			ServiceID: "redis",
+			Current:   &catalogdiff.ConsulService{ID: "redis", Service: "redis", Port: 6379},
+			Cascaded:  true,
+			Line:      7,
+		}},
+		CheckDeletions: []catalogdiff.CheckDiff{{
+			Node:     "web-009",
+			CheckID:  "serfHealth",
+			Current:  &catalogdiff.ConsulCheck{Node: "web-009", CheckID: "serfHealth"},
+			Cascaded: true,
+			Line:     7,
+		}},
+		Conflicts: []catalogdiff.OperationConflict{{
+			Kind:        "service",
+			Target:      "web-001/nginx",
+			Lines:       []int{2, 5},
+			Conflicting: true,
+			Reason:      "set operations with different values",
+		}},
+		Ignored: []catalogdiff.IgnoredDiff{{
+			Kind:   "node",
+			Target: "web-001",
+			Change: "modification",
+			Field:  "Meta.last-deploy",
+			Rule:   catalogdiff.IgnoreRule{Kind: "node", Target: "web-*", Field: "Meta.last-deploy", Line: 1},
+		}},
+	}
+}
+
+// goldenOperations returns the payload goldenDiff was computed from
+func goldenOperations() []catalogdiff.Operation {
+	node := func(verb, name string, line int) catalogdiff.Operation {
+		return catalogdiff.Operation{Node: &catalogdiff.NodeOperation{Verb: verb, Node: map[string]interface{}{"Node": name}}, Line: line}
+	}
+	service := func(verb, nodeName, id string, line int) catalogdiff.Operation {
+		return catalogdiff.Operation{Service: &catalogdiff.ServiceOperation{Verb: verb, Node: nodeName, Service: map[string]interface{}{"ID": id}}, Line: line}
+	}
+
+	return []catalogdiff.Operation{
+		node("set", "web-001", 1),
+		service("set", "web-001", "nginx", 2),
+		node("set", "web-002", 3),
+		node("set", "web-003", 4),
+		service("set", "web-001", "nginx", 5),
+		service("set", "web-003", "nginx", 6),
+		node("delete", "web-009", 7),
+		{Check: &catalogdiff.CheckOperation{Verb: "set", Node: "web-001", Check: map[string]interface{}{"CheckID": "mem"}}, Line: 8},
+		node("set", "web-004", 9),
+		service("set", "web-004", "metrics", 10),
+	}
+}
+
+// checkGolden compares output with a golden file, rewriting it with -update
+func checkGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := os.WriteFile(path, got, 0644); err != nil {
+			t.Fatalf("failed to update golden file: %v", err)
+		}
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read golden file: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("output does not match %s\ngot:\n%s\nwant:\n%s", path, got, want)
+	}
+}
+
+// unified adapts outputUnified to the signature of the other renderers
+func unified(r *reportWriter, diff *catalogdiff.DiffResult) error {
+	r.outputUnified(diff)
+	return nil
+}
+
+func TestOutputGolden(t *testing.T) {
+	tests := []struct {
+		golden string
+		color  bool
+		render func(*reportWriter, *catalogdiff.DiffResult) error
+	}{
+		{"report.golden", false, (*reportWriter).outputDiff},
+		{"report_color.golden", true, (*reportWriter).outputDiff},
+		{"unified.golden", false, unified},
+		{"unified_color.golden", true, unified},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.golden, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := tt.render(newReportWriter(&buf, tt.color), goldenDiff()); err != nil {
+				t.Fatalf("render error = %v", err)
+			}
+
+			if !tt.color && strings.Contains(buf.String(), "\033[") {
+				t.Errorf("output contains escape sequences with color disabled")
+			}
+			checkGolden(t, tt.golden, buf.Bytes())
+		})
+	}
+}
+
+func TestOutputJUnit(t *testing.T) {
+	var buf bytes.Buffer
+	if err := newReportWriter(&buf, true).outputJUnit(goldenOperations(), goldenDiff()); err != nil {
+		t.Fatalf("outputJUnit() error = %v", err)
+	}
+	checkGolden(t, "junit.golden", buf.Bytes())
+}
+
+func TestOutputGitHub(t *testing.T) {
+	summaryPath := filepath.Join(t.TempDir(), "summary.md")
+	t.Setenv("GITHUB_STEP_SUMMARY", summaryPath)
+
+	var buf bytes.Buffer
+	if err := newReportWriter(&buf, false).outputGitHub("catalog/operations.ndjson", goldenDiff()); err != nil {
+		t.Fatalf("outputGitHub() error = %v", err)
+	}
+	checkGolden(t, "github.golden", buf.Bytes())
+
+	summary, err := os.ReadFile(summaryPath)
+	if err != nil {
+		t.Fatalf("failed to read job summary: %v", err)
+	}
+	checkGolden(t, "github_summary.golden", summary)
+}
+
+func TestOutputSARIF(t *testing.T) {
+	var buf bytes.Buffer
+	if err := newReportWriter(&buf, false).outputSARIF("catalog/operations.ndjson", goldenDiff()); err != nil {
+		t.Fatalf("outputSARIF() error = %v", err)
+	}
+	checkGolden(t, "sarif.golden", buf.Bytes())
+
+	// The same drift at another line must keep its fingerprint
+	moved := goldenDiff()
+	moved.NodeModifications[0].Line = 42
+	moved.NodeModifications[0].Fields[0].Expected = "10.0.0.200"
+	change := collectChanges(moved)[1]
+	want := sarifFingerprint("catalog/node-field-drift", collectChanges(goldenDiff())[1])
+	if got := sarifFingerprint("catalog/node-field-drift", change); got != want {
+		t.Errorf("sarifFingerprint() = %s, want %s", got, want)
+	}
+}
+
+func TestSarifArtifactURI(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Getwd() error = %v", err)
+	}
+	parent := filepath.Dir(wd)
+
+	tests := []struct {
+		file string
+		want string
+	}{
+		{"catalog/operations.ndjson", "catalog/operations.ndjson"},
+		{"./catalog/../catalog/operations.ndjson", "catalog/operations.ndjson"},
+		{filepath.Join(wd, "catalog", "operations.ndjson"), "catalog/operations.ndjson"},
+		{"catalog/my ops.ndjson", "catalog/my%20ops.ndjson"},
+		{"../operations.ndjson", "file://" + filepath.ToSlash(filepath.Join(parent, "operations.ndjson"))},
+	}
+
+	for _, tt := range tests {
+		if got := sarifArtifactURI(tt.file); got != tt.want {
+			t.Errorf("sarifArtifactURI(%q) = %q, want %q", tt.file, got, tt.want)
+		}
+	}
+}
+
+func TestOutputHTML(t *testing.T) {
+	var buf bytes.Buffer
+	generated := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	if err := newReportWriter(&buf, false).outputHTML(goldenDiff(), generated); err != nil {
+		t.Fatalf("outputHTML() error = %v", err)
+	}
+	checkGolden(t, "report_html.golden", buf.Bytes())
+}
+
+func TestSideBySide(t *testing.T) {
+	rows := sideBySide([]string{"{", `  "Port": 80`, "}"}, []string{"{", `  "Port": 8080`, `  "Tags": []`, "}"})
+	want := []sideBySideRow{
+		{Current: "{", Expected: "{"},
+		{Current: `  "Port": 80`, Expected: `  "Port": 8080`, CurrentChanged: true, ExpectedChanged: true},
+		{Expected: `  "Tags": []`, ExpectedChanged: true},
+		{Current: "}", Expected: "}"},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("sideBySide() = %+v, want %+v", rows, want)
+	}
+}
+
+func TestNewHTMLChangeComparatorFields(t *testing.T) {
+	// Tags differ only in order and Meta only in type, so only Port changes
+	c := targetChange{
+		Kind:     "service",
+		Target:   "web-001/nginx",
+		Change:   "modification",
+		Fields:   []catalogdiff.FieldDiff{{Field: "Port", Expected: 8080, Current: 80}},
+		Expected: map[string]interface{}{"ID": "nginx", "Port": float64(8080), "Tags": []interface{}{"web", "primary"}, "Meta": map[string]interface{}{"version": float64(2)}},
+		Current:  catalogdiff.ToJSONValue(catalogdiff.ConsulService{ID: "nginx", Port: 80, Tags: []string{"primary", "web"}, Meta: map[string]string{"version": "2"}}),
+	}
+
+	var changed []sideBySideRow
+	for _, row := range newHTMLChange(c).Rows {
+		if row.CurrentChanged || row.ExpectedChanged {
+			changed = append(changed, row)
+		}
+	}
+
+	want := []sideBySideRow{{Current: `  "Port": 80,`, Expected: `  "Port": 8080,`, CurrentChanged: true, ExpectedChanged: true}}
+	if !reflect.DeepEqual(changed, want) {
+		t.Errorf("changed rows = %+v, want %+v", changed, want)
+	}
+}
+
+func TestOutputTemplate(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{
+			name: "counts",
+			text: `{{count "add" .}} added, {{count "modifications" .}} modified, {{count "delete" .}} deleted`,
+			want: "4 added, 2 modified, 3 deleted",
+		},
+		{
+			name: "changes",
+			text: `{{range changes .}}{{if eq .Change "modification"}}{{.Name}}: {{.Details | truncate 20}};{{end}}{{end}}`,
+			want: "node/web-001: Address: 10.0.0.1...;service/web-001/nginx: Port: 80 -> 8080;",
+		},
+		{
+			name: "join and json",
+			text: `{{range .ServiceAdditions}}{{join "," .Expected.Tags}} {{json .Expected.Port}};{{end}}`,
+			want: "web,primary 80;<nil> 0;",
+		},
+		{
+			name: "has and kind",
+			text: `{{range .ServiceAdditions}}{{has .Expected "Port"}} {{has .Expected "Meta"}} {{kind .Expected.Tags}} {{kind .Expected.Service}};{{end}}`,
+			want: "true false list string;true false null string;",
+		},
+		{
+			name: "color",
+			text: `{{color "red" "drift"}}`,
+			want: "\033[31mdrift\033[0m",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl, err := newReportTemplate(tt.name, tt.text)
+			if err != nil {
+				t.Fatalf("newReportTemplate() error = %v", err)
+			}
+
+			var buf bytes.Buffer
+			if err := newReportWriter(&buf, true).outputTemplate(tmpl, goldenDiff()); err != nil {
+				t.Fatalf("outputTemplate() error = %v", err)
+			}
+			if got := buf.String(); got != tt.want {
+				t.Errorf("outputTemplate() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOutputTemplateShared(t *testing.T) {
+	tmpl, err := newReportTemplate("shared", `{{color "red" "drift"}}`)
+	if err != nil {
+		t.Fatalf("newReportTemplate() error = %v", err)
+	}
+
+	var colored bytes.Buffer
+	if err := newReportWriter(&colored, true).outputTemplate(tmpl, goldenDiff()); err != nil {
+		t.Fatalf("outputTemplate() error = %v", err)
+	}
+
+	// Rendering with color must not rebind the functions of the shared template
+	var plain bytes.Buffer
+	if err := tmpl.Execute(&plain, goldenDiff()); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if got := plain.String(); got != "drift" {
+		t.Errorf("shared template rendered %q after a colored render, want %q", got, "drift")
+	}
+}
+
+func TestOutputNoChanges(t *testing.T) {
+	var buf bytes.Buffer
+	if err := newReportWriter(&buf, true).outputDiff(&catalogdiff.DiffResult{}); err != nil {
+		t.Fatalf("outputDiff() error = %v", err)
+	}
+	if got := buf.String(); got != "No differences found\n" {
+		t.Errorf("outputDiff() = %q, want %q", got, "No differences found\n")
+	}
+
+	buf.Reset()
+	if err := newReportWriter(&buf, true).outputDiff(&catalogdiff.DiffResult{Ignored: goldenDiff().Ignored}); err != nil {
+		t.Fatalf("outputDiff() error = %v", err)
+	}
+	if got := buf.String(); got != "No differences found (1 ignored)\n" {
+		t.Errorf("outputDiff() = %q, want %q", got, "No differences found (1 ignored)\n")
+	}
+}
+
+func TestOutputReads(t *testing.T) {
+	tests := []struct {
+		name  string
+		diff  *catalogdiff.DiffResult
+		reads catalogdiff.ReadStats
+		want  string
+	}{
+		{
+			name:  "no changes",
+			diff:  &catalogdiff.DiffResult{},
+			reads: catalogdiff.ReadStats{Consistency: "stale", Responses: 3, MaxLastContact: 12 * time.Millisecond},
+			want:  "No differences found\nConsistency: stale (last contact 12ms, known leader: yes)\n",
+		},
+		{
+			name:  "no headers",
+			diff:  &catalogdiff.DiffResult{},
+			reads: catalogdiff.ReadStats{Consistency: "default"},
+			want:  "No differences found\nConsistency: default (no X-Consul-LastContact headers observed)\n",
+		},
+		{
+			name: "lagging",
+			diff: goldenDiff(),
+			reads: catalogdiff.ReadStats{
+				Consistency:    "stale",
+				Responses:      2,
+				MaxLastContact: 8 * time.Second,
+				NoKnownLeader:  true,
+				Lagging:        true,
+				MaxStaleLag:    5 * time.Second,
+			},
+			want: "=== Consul Catalog Diff Report ===\n" +
+				"Consistency: stale (last contact 8s, known leader: no)\n" +
+				"WARNING: reads lag 8s behind the leader, more than 5s\n" +
+				"Total changes: ",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.diff.Reads = &tt.reads
+
+			var buf bytes.Buffer
+			if err := newReportWriter(&buf, false).outputDiff(tt.diff); err != nil {
+				t.Fatalf("outputDiff() error = %v", err)
+			}
+			if got := buf.String(); !strings.HasPrefix(got, tt.want) {
+				t.Errorf("outputDiff() = %q, want prefix %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOutputReadsFormats(t *testing.T) {
+	t.Setenv("GITHUB_STEP_SUMMARY", "")
+
+	reads := catalogdiff.ReadStats{
+		Consistency:    "stale",
+		Responses:      2,
+		MaxLastContact: 8 * time.Second,
+		NoKnownLeader:  true,
+		Lagging:        true,
+		MaxStaleLag:    5 * time.Second,
+	}
+
+	tests := []struct {
+		name   string
+		render func(*reportWriter, *catalogdiff.DiffResult) error
+		want   []string
+	}{
+		{
+			name:   "unified",
+			render: unified,
+			want: []string{
+				"Consistency: stale (last contact 8s, known leader: no)\n" +
+					"WARNING: reads lag 8s behind the leader, more than 5s\n" +
+					"WARNING: Consul reported no known leader while reading the catalog\n" +
+					"--- /dev/null\n",
+			},
+		},
+		{
+			name: "junit",
+			render: func(r *reportWriter, diff *catalogdiff.DiffResult) error {
+				return r.outputJUnit(goldenOperations(), diff)
+			},
+			want: []string{
+				`<property name="consistency" value="Consistency: stale (last contact 8s, known leader: no)"></property>`,
+				`<property name="warning" value="reads lag 8s behind the leader, more than 5s"></property>`,
+			},
+		},
+		{
+			name: "github",
+			render: func(r *reportWriter, diff *catalogdiff.DiffResult) error {
+				return r.outputGitHub("", diff)
+			},
+			want: []string{
+				"::notice title=Consul reads::Consistency: stale (last contact 8s, known leader: no)\n",
+				"::warning title=Consul reads::reads lag 8s behind the leader, more than 5s\n",
+			},
+		},
+		{
+			name: "sarif",
+			render: func(r *reportWriter, diff *catalogdiff.DiffResult) error {
+				return r.outputSARIF("", diff)
+			},
+			want: []string{
+				`"consistency": "stale"`,
+				`"maxLastContact": "8s"`,
+				`"text": "Consul reported no known leader while reading the catalog"`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diff := goldenDiff()
+			diff.Reads = &reads
+
+			var buf bytes.Buffer
+			if err := tt.render(newReportWriter(&buf, false), diff); err != nil {
+				t.Fatalf("render error = %v", err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(buf.String(), want) {
+					t.Errorf("output does not contain %q:\n%s", want, buf.String())
+				}
+			}
+		})
+	}
+}
+
+func TestUnifiedHunks(t *testing.T) {
+	a := []string{"{", `  "a": 1,`, `  "b": 2,`, `  "c": 3,`, `  "d": 4,`, `  "e": 5,`, `  "f": 6,`, `  "g": 7,`, `  "h": 8`, "}"}
+	b := []string{"{", `  "a": 1,`, `  "b": 20,`, `  "c": 3,`, `  "d": 4,`, `  "e": 5,`, `  "f": 6,`, `  "g": 7,`, `  "h": 8`, "}"}
+
+	hunks := unifiedHunks(a, b, 3)
+	if len(hunks) != 1 {
+		t.Fatalf("unifiedHunks() returned %d hunks, want 1", len(hunks))
+	}
+
+	want := "@@ -1,6 +1,6 @@\n" +
+		" {\n" +
+		"   \"a\": 1,\n" +
+		"-  \"b\": 2,\n" +
+		"+  \"b\": 20,\n" +
+		"   \"c\": 3,\n" +
+		"   \"d\": 4,\n" +
+		"   \"e\": 5,\n"
+	if hunks[0] != want {
+		t.Errorf("unifiedHunks() =\n%s\nwant\n%s", hunks[0], want)
+	}
+
+	if hunks := unifiedHunks(nil, []string{"{", "}"}, 3); len(hunks) != 1 || hunks[0] != "@@ -0,0 +1,2 @@\n+{\n+}\n" {
+		t.Errorf("unifiedHunks() for addition = %q", hunks)
+	}
+}
+
+func TestOutputUnifiedComparatorFields(t *testing.T) {
+	// Tags differ only in order, Meta only in type and Meta.build is
+	// ignored, so only Port and Proxy.Upstreams[0] may show up
+	diff := &catalogdiff.DiffResult{
+		ServiceModifications: []catalogdiff.ServiceDiff{{
+			Node:      "web-001",
+			ServiceID: "nginx",
+			Expected: map[string]interface{}{
+				"ID":    "nginx",
+				"Port":  float64(8080),
+				"Tags":  []interface{}{"web", "primary"},
+				"Meta":  map[string]interface{}{"version": float64(2), "build": "42"},
+				"Proxy": map[string]interface{}{"Upstreams": []interface{}{map[string]interface{}{"LocalBindPort": float64(9191)}}},
+			},
+			Current: &catalogdiff.ConsulService{
+				ID:   "nginx",
+				Port: 80,
+				Tags: []string{"primary", "web"},
+				Meta: map[string]string{"version": "2", "build": "41"},
+				Proxy: &catalogdiff.ServiceProxy{
+					Upstreams: []catalogdiff.ProxyUpstream{{LocalBindPort: 9292}},
+				},
+			},
+			Fields: []catalogdiff.FieldDiff{
+				{Field: "Port", Expected: 8080, Current: 80},
+				{Field: "Proxy.Upstreams[0].LocalBindPort", Expected: 9191, Current: 9292},
+			},
+		}},
+	}
+
+	var buf bytes.Buffer
+	newReportWriter(&buf, false).outputUnified(diff)
+
+	var changed []string
+	for _, line := range strings.Split(buf.String(), "\n") {
+		if (strings.HasPrefix(line, "-") || strings.HasPrefix(line, "+")) &&
+			!strings.HasPrefix(line, "---") && !strings.HasPrefix(line, "+++") {
+			changed = append(changed, strings.TrimSpace(line[1:]))
+		}
+	}
+
+	want := []string{`"Port": 80,`, `"Port": 8080,`, `"LocalBindPort": 9292`, `"LocalBindPort": 9191`}
+	if !reflect.DeepEqual(changed, want) {
+		t.Errorf("changed lines = %q, want %q\n%s", changed, want, buf.String())
+	}
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/zinrai/consul-catalog-diff/catalogdiff"
+)
+
+// Exit codes returned when a -fail-on policy matches, by severity
+const (
+	exitChanges       = 1
+	exitAdditions     = 3
+	exitModifications = 4
+	exitOrphans       = 5
+	exitDeletions     = 6
+)
+
+// failCategories maps each category accepted by -fail-on to its exit code
+var failCategories = map[string]int{
+	"any":            exitChanges,
+	"add":            exitAdditions,
+	"node-add":       exitAdditions,
+	"service-add":    exitAdditions,
+	"modify":         exitModifications,
+	"node-modify":    exitModifications,
+	"service-modify": exitModifications,
+	"orphan":         exitOrphans,
+	"delete":         exitDeletions,
+	"node-delete":    exitDeletions,
+	"service-delete": exitDeletions,
+	"check-delete":   exitDeletions,
+}
+
+// failCategoryAliases maps plural forms to categories
+var failCategoryAliases = map[string]string{
+	"changes":       "any",
+	"additions":     "add",
+	"modifications": "modify",
+	"deletions":     "delete",
+	"orphans":       "orphan",
+}
+
+// FailTerm represents a single -fail-on condition such as "delete" or "modifications>5"
+type FailTerm struct {
+	Category  string
+	Inclusive bool // ">=" instead of ">"
+	Threshold int
+}
+
+// String returns the term in -fail-on syntax
+func (t FailTerm) String() string {
+	op := ">"
+	if t.Inclusive {
+		op = ">="
+	}
+	return fmt.Sprintf("%s%s%d", t.Category, op, t.Threshold)
+}
+
+// parseFailTerms parses a comma-separated -fail-on value
+func parseFailTerms(value string) ([]FailTerm, error) {
+	var terms []FailTerm
+	for _, item := range splitList(value) {
+		term, err := parseFailTerm(item)
+		if err != nil {
+			return nil, err
+		}
+		terms = append(terms, term)
+	}
+	return terms, nil
+}
+
+// parseFailTerm parses "CATEGORY", "CATEGORY>N" or "CATEGORY>=N"
+func parseFailTerm(s string) (FailTerm, error) {
+	var term FailTerm
+	name := s
+
+	if idx := strings.Index(s, ">"); idx >= 0 {
+		name = s[:idx]
+		rest := s[idx+1:]
+		if strings.HasPrefix(rest, "=") {
+			term.Inclusive = true
+			rest = rest[1:]
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(rest))
+		if err != nil || n < 0 {
+			return term, fmt.Errorf("invalid threshold in %q", s)
+		}
+		term.Threshold = n
+	}
+
+	name = strings.TrimSpace(name)
+	if alias, ok := failCategoryAliases[name]; ok {
+		name = alias
+	}
+	if _, ok := failCategories[name]; !ok {
+		return term, fmt.Errorf("unknown category %q in %q", name, s)
+	}
+	term.Category = name
+
+	return term, nil
+}
+
+// matches checks if the number of changes in the category exceeds the threshold
+func (t FailTerm) matches(diff *catalogdiff.DiffResult) bool {
+	count := countCategory(diff, t.Category)
+	if t.Inclusive {
+		return count >= t.Threshold
+	}
+	return count > t.Threshold
+}
+
+// countCategory returns the number of changes in a -fail-on category
+func countCategory(diff *catalogdiff.DiffResult, category string) int {
+	switch category {
+	case "node-add":
+		return len(diff.NodeAdditions)
+	case "service-add":
+		return len(diff.ServiceAdditions)
+	case "add":
+		return len(diff.NodeAdditions) + len(diff.ServiceAdditions)
+	case "node-modify":
+		return len(diff.NodeModifications)
+	case "service-modify":
+		return len(diff.ServiceModifications)
+	case "modify":
+		return len(diff.NodeModifications) + len(diff.ServiceModifications)
+	case "node-delete":
+		return len(diff.NodeDeletions)
+	case "service-delete":
+		return len(diff.ServiceDeletions)
+	case "check-delete":
+		return len(diff.CheckDeletions)
+	case "delete":
+		return len(diff.NodeDeletions) + len(diff.ServiceDeletions) + len(diff.CheckDeletions)
+	case "orphan":
+		count := 0
+		for _, add := range diff.ServiceAdditions {
+			if add.Orphan {
+				count++
+			}
+		}
+		return count
+	default:
+		return diff.TotalChanges()
+	}
+}
+
+// exitCodeFor returns the exit code for a diff under a -fail-on policy.
+// Without a policy any change fails with exit code 1. Otherwise the exit
+// code of the most severe matching term is returned, and changes that
+// match no term are only logged.
+func exitCodeFor(diff *catalogdiff.DiffResult, terms []FailTerm) int {
+	if len(terms) == 0 {
+		if diff.HasChanges() {
+			return exitChanges
+		}
+		return 0
+	}
+
+	code := 0
+	for _, term := range terms {
+		if term.matches(diff) {
+			log.Printf("[INFO] Failing on %s (%d found)", term, countCategory(diff, term.Category))
+			if c := failCategories[term.Category]; c > code {
+				code = c
+			}
+		}
+	}
+
+	if code == 0 && diff.HasChanges() {
+		log.Printf("[WARN] %d change(s) found, none matching -fail-on", diff.TotalChanges())
+	}
+
+	return code
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/zinrai/consul-catalog-diff/catalogdiff"
+)
+
+func TestExitCodeFor(t *testing.T) {
+	diff := &catalogdiff.DiffResult{
+		NodeAdditions:        []catalogdiff.NodeDiff{{Node: "web-003"}},
+		ServiceAdditions:     []catalogdiff.ServiceDiff{{Node: "web-009", ServiceID: "nginx", Orphan: true}},
+		ServiceModifications: []catalogdiff.ServiceDiff{{Node: "web-001", ServiceID: "nginx"}, {Node: "web-002", ServiceID: "nginx"}},
+	}
+
+	tests := []struct {
+		policy string
+		want   int
+	}{
+		{"", exitChanges},
+		{"any", exitChanges},
+		{"delete", 0},
+		{"add", exitAdditions},
+		{"node-add,service-modify", exitModifications},
+		{"modifications>2", 0},
+		{"modifications>=2", exitModifications},
+		{"add,orphan,delete", exitOrphans},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			terms, err := parseFailTerms(tt.policy)
+			if err != nil {
+				t.Fatalf("parseFailTerms(%q) error = %v", tt.policy, err)
+			}
+			if got := exitCodeFor(diff, terms); got != tt.want {
+				t.Errorf("exitCodeFor() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+
+	for _, invalid := range []string{"create", "delete>x", "modify>-1"} {
+		if _, err := parseFailTerms(invalid); err == nil {
+			t.Errorf("parseFailTerms(%q) succeeded, want error", invalid)
+		}
+	}
+}
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/zinrai/consul-catalog-diff/catalogdiff"
+)
+
+const (
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+)
+
+// sarifRule describes one category of change
+type sarifRule struct {
+	ID               string             `json:"id"`
+	Name             string             `json:"name"`
+	ShortDescription sarifMessage       `json:"shortDescription"`
+	DefaultConfig    sarifConfiguration `json:"defaultConfiguration"`
+}
+
+// sarifRules lists the rules in output order, keyed by "kind:change"
+var sarifRules = []struct {
+	key  string
+	rule sarifRule
+}{
+	{"node:addition", newSarifRule("catalog/node-missing", "NodeMissing", "Node in the payload is not registered in Consul", "warning")},
+	{"node:modification", newSarifRule("catalog/node-field-drift", "NodeFieldDrift", "Node fields in Consul differ from the payload", "warning")},
+	{"node:deletion", newSarifRule("catalog/node-not-deleted", "NodeNotDeleted", "Node deleted in the payload is still registered in Consul", "error")},
+	{"service:addition", newSarifRule("catalog/service-missing", "ServiceMissing", "Service in the payload is not registered in Consul", "warning")},
+	{"service:modification", newSarifRule("catalog/service-field-drift", "ServiceFieldDrift", "Service fields in Consul differ from the payload", "warning")},
+	{"service:deletion", newSarifRule("catalog/service-not-deleted", "ServiceNotDeleted", "Service deleted in the payload is still registered in Consul", "error")},
+	{"check:deletion", newSarifRule("catalog/check-not-deleted", "CheckNotDeleted", "Check on a node deleted in the payload is still registered in Consul", "error")},
+}
+
+// newSarifRule creates a rule with a default level
+func newSarifRule(id, name, description, level string) sarifRule {
+	return sarifRule{
+		ID:               id,
+		Name:             name,
+		ShortDescription: sarifMessage{Text: description},
+		DefaultConfig:    sarifConfiguration{Level: level},
+	}
+}
+
+// sarifLog is the root object of a SARIF log
+type sarifLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+// sarifRun holds the results of one run of the tool
+type sarifRun struct {
+	Tool        sarifTool              `json:"tool"`
+	Invocations []sarifInvocation      `json:"invocations,omitempty"`
+	Results     []sarifResult          `json:"results"`
+	Properties  map[string]interface{} `json:"properties,omitempty"`
+}
+
+// sarifInvocation reports warnings about the run itself
+type sarifInvocation struct {
+	ExecutionSuccessful bool                `json:"executionSuccessful"`
+	Notifications       []sarifNotification `json:"toolExecutionNotifications"`
+}
+
+// sarifNotification describes a condition of the run, not of the payload
+type sarifNotification struct {
+	Level   string       `json:"level"`
+	Message sarifMessage `json:"message"`
+}
+
+// sarifTool describes the tool that produced a run
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+// sarifDriver describes the tool and the rules it reports
+type sarifDriver struct {
+	Name    string      `json:"name"`
+	Version string      `json:"version"`
+	Rules   []sarifRule `json:"rules"`
+}
+
+// sarifConfiguration holds the default level of a rule
+type sarifConfiguration struct {
+	Level string `json:"level"`
+}
+
+// sarifMessage holds the text of a message
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+// sarifResult describes one change
+type sarifResult struct {
+	RuleID       string                 `json:"ruleId"`
+	RuleIndex    int                    `json:"ruleIndex"`
+	Level        string                 `json:"level"`
+	Message      sarifMessage           `json:"message"`
+	Locations    []sarifLocation        `json:"locations,omitempty"`
+	Fingerprints map[string]string      `json:"fingerprints"`
+	Properties   map[string]interface{} `json:"properties"`
+}
+
+// sarifLocation points to the operation a result was detected for
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+// sarifPhysicalLocation points to a file and region
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+// sarifArtifactLocation holds the URI of a file
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+// sarifRegion holds the line of a location
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+}
+
+// outputSARIF outputs a SARIF 2.1.0 log with one result per change,
+// located at the line of its operation in file
+func (r *reportWriter) outputSARIF(file string, diff *catalogdiff.DiffResult) error {
+	driver := sarifDriver{Name: binaryName, Version: version}
+	ruleIndexes := make(map[string]int)
+	for i, entry := range sarifRules {
+		driver.Rules = append(driver.Rules, entry.rule)
+		ruleIndexes[entry.key] = i
+	}
+
+	var artifactURI string
+	if file != "" {
+		artifactURI = sarifArtifactURI(file)
+	}
+
+	results := []sarifResult{}
+	for _, c := range collectChanges(diff) {
+		index, ok := ruleIndexes[c.Kind+":"+c.Change]
+		if !ok {
+			continue
+		}
+		rule := sarifRules[index].rule
+
+		message := c.Summary()
+		if details := c.Details(); details != "" {
+			message += "\n" + details
+		}
+
+		result := sarifResult{
+			RuleID:       rule.ID,
+			RuleIndex:    index,
+			Level:        rule.DefaultConfig.Level,
+			Message:      sarifMessage{Text: message},
+			Fingerprints: map[string]string{"catalogDiff/v1": sarifFingerprint(rule.ID, c)},
+			Properties: map[string]interface{}{
+				"target":   c.Name(),
+				"cascaded": c.Cascaded,
+				"orphan":   c.Orphan,
+			},
+		}
+
+		if file != "" {
+			location := sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifactLocation{URI: artifactURI},
+			}
+			if c.Line > 0 {
+				location.Region = &sarifRegion{StartLine: c.Line}
+			}
+			result.Locations = []sarifLocation{{PhysicalLocation: location}}
+		}
+
+		results = append(results, result)
+	}
+
+	run := sarifRun{Tool: sarifTool{Driver: driver}, Results: results}
+	if reads := diff.Reads; reads != nil {
+		run.Properties = map[string]interface{}{
+			"consistency":    reads.Consistency,
+			"responses":      reads.Responses,
+			"maxLastContact": reads.MaxLastContact.String(),
+			"knownLeader":    !reads.NoKnownLeader,
+			"lagging":        reads.Lagging,
+		}
+		if warnings := readsWarnings(reads); len(warnings) > 0 {
+			invocation := sarifInvocation{ExecutionSuccessful: true}
+			for _, w := range warnings {
+				invocation.Notifications = append(invocation.Notifications, sarifNotification{Level: "warning", Message: sarifMessage{Text: w}})
+			}
+			run.Invocations = []sarifInvocation{invocation}
+		}
+	}
+
+	report := sarifLog{
+		Version: sarifVersion,
+		Schema:  sarifSchema,
+		Runs:    []sarifRun{run},
+	}
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode SARIF log: %w", err)
+	}
+
+	r.println(string(data))
+	return nil
+}
+
+// sarifArtifactURI returns the URI of the payload file: a path relative to
+// the working directory, which code scanning resolves against the checkout,
+// or a file:// URI for files outside of it
+func sarifArtifactURI(file string) string {
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return (&url.URL{Path: filepath.ToSlash(file)}).String()
+	}
+
+	if wd, err := os.Getwd(); err == nil {
+		rel, err := filepath.Rel(wd, abs)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return (&url.URL{Path: filepath.ToSlash(rel)}).String()
+		}
+	}
+
+	path := filepath.ToSlash(abs)
+	if !strings.HasPrefix(path, "/") {
+		// Windows paths such as C:/x become file:///C:/x
+		path = "/" + path
+	}
+	return (&url.URL{Scheme: "file", Path: path}).String()
+}
+
+// sarifFingerprint identifies a change across runs. It depends on the rule,
+// the target and the names of the differing fields, but not on line numbers
+// or values, so the same drift is deduplicated even as the payload changes.
+func sarifFingerprint(ruleID string, c targetChange) string {
+	fields := make([]string, len(c.Fields))
+	for i, f := range c.Fields {
+		fields[i] = f.Field
+	}
+
+	sum := sha256.Sum256([]byte(strings.Join([]string{ruleID, c.Name(), strings.Join(fields, ",")}, "\n")))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"text/template"
+
+	"github.com/zinrai/consul-catalog-diff/catalogdiff"
+)
+
+//go:embed templates/report.txt
+var textReportTemplate string
+
+// defaultReportTemplate renders the built-in text report
+var defaultReportTemplate = template.Must(newReportTemplate("report.txt", textReportTemplate))
+
+// templateColors maps color names accepted by the color template function
+var templateColors = map[string]string{
+	"red":    colorRed,
+	"green":  colorGreen,
+	"yellow": colorYellow,
+	"cyan":   colorCyan,
+	"bold":   colorBold,
+	"dim":    colorDim,
+}
+
+// newReportTemplate parses a report template with the helper functions
+func newReportTemplate(name, text string) (*template.Template, error) {
+	return template.New(name).Funcs((&reportWriter{}).templateFuncs()).Parse(text)
+}
+
+// loadReportTemplate loads and parses a -template file
+func loadReportTemplate(filename string) (*template.Template, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read template: %w", err)
+	}
+
+	tmpl, err := newReportTemplate(filename, string(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// outputTemplate renders diff with a report template
+func (r *reportWriter) outputTemplate(tmpl *template.Template, diff *catalogdiff.DiffResult) error {
+	// Rebind the functions on a copy, so that color follows this writer's
+	// setting without changing a template shared with other writers
+	tmpl, err := tmpl.Clone()
+	if err != nil {
+		return fmt.Errorf("failed to copy template: %w", err)
+	}
+	if err := tmpl.Funcs(r.templateFuncs()).Execute(r.w, diff); err != nil {
+		return fmt.Errorf("failed to render template: %w", err)
+	}
+	return nil
+}
+
+// jsonKind returns the JSON kind of a decoded payload value
+func jsonKind(v interface{}) string {
+	if v == nil {
+		return "null"
+	}
+	switch reflect.ValueOf(v).Kind() {
+	case reflect.Bool:
+		return "bool"
+	case reflect.String:
+		return "string"
+	case reflect.Slice, reflect.Array:
+		return "list"
+	case reflect.Map, reflect.Struct:
+		return "map"
+	default:
+		return "number"
+	}
+}
+
+// templateFuncs returns the helper functions available to report templates
+func (r *reportWriter) templateFuncs() template.FuncMap {
+	return template.FuncMap{
+		// count returns the number of changes in a -fail-on category
+		"count": func(category string, diff *catalogdiff.DiffResult) (int, error) {
+			if alias, ok := failCategoryAliases[category]; ok {
+				category = alias
+			}
+			if _, ok := failCategories[category]; !ok {
+				return 0, fmt.Errorf("unknown category %q", category)
+			}
+			return countCategory(diff, category), nil
+		},
+
+		// changes returns every change as a flat list
+		"changes": collectChanges,
+
+		// join joins the elements of a list with sep
+		"join": func(sep string, list interface{}) string {
+			switch l := list.(type) {
+			case []string:
+				return strings.Join(l, sep)
+			case []interface{}:
+				strs := make([]string, len(l))
+				for i, v := range l {
+					strs[i] = fmt.Sprint(v)
+				}
+				return strings.Join(strs, sep)
+			default:
+				return fmt.Sprint(list)
+			}
+		},
+
+		// color colors text when color output is enabled
+		"color": func(name, text string) (string, error) {
+			code, ok := templateColors[name]
+			if !ok {
+				return "", fmt.Errorf("unknown color %q", name)
+			}
+			return r.colorize(code, text), nil
+		},
+
+		// json encodes a value as JSON
+		"json": func(v interface{}) (string, error) {
+			data, err := json.Marshal(v)
+			if err != nil {
+				return "", err
+			}
+			return string(data), nil
+		},
+
+		// truncate shortens text to n characters, ending it with "..."
+		"truncate": func(n int, text string) string {
+			runes := []rune(text)
+			if len(runes) <= n {
+				return text
+			}
+			if n <= 3 {
+				return string(runes[:n])
+			}
+			return string(runes[:n-3]) + "..."
+		},
+
+		// value formats a field value, showing nil as "(unset)"
+		"value": formatFieldValue,
+
+		// has reports whether a payload has a key, even if its value is empty
+		"has": func(m map[string]interface{}, key string) bool {
+			_, ok := m[key]
+			return ok
+		},
+
+		// kind returns the JSON kind of a value: null, bool, number, string,
+		// list or map
+		"kind": jsonKind,
+
+		// lines formats line numbers as a comma-separated list
+		"lines": func(lines []int) string {
+			strs := make([]string, len(lines))
+			for i, line := range lines {
+				strs[i] = fmt.Sprint(line)
+			}
+			return strings.Join(strs, ", ")
+		},
+
+		// last returns the last line number of a list
+		"last": func(lines []int) int {
+			if len(lines) == 0 {
+				return 0
+			}
+			return lines[len(lines)-1]
+		},
+	}
+}
@@ -0,0 +1,329 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/zinrai/consul-catalog-diff/catalogdiff"
+)
+
+// unifiedContext is the number of unchanged lines shown around each change
+const unifiedContext = 3
+
+// outputUnified outputs each changed node, service and check as a unified
+// diff between its current state in Consul and the expected payload. The
+// consistency of the Consul reads precedes the diffs, where patch tools
+// ignore it.
+func (r *reportWriter) outputUnified(diff *catalogdiff.DiffResult) {
+	if diff.Reads != nil {
+		r.println(readsSummary(diff.Reads))
+		for _, w := range readsWarnings(diff.Reads) {
+			r.println(r.colorize(colorRed, "WARNING: "+w))
+		}
+	}
+
+	for _, d := range diff.NodeAdditions {
+		r.outputUnifiedDocument("node/"+d.Node, nil, d.Expected)
+	}
+	for _, d := range diff.NodeModifications {
+		r.outputUnifiedDocument("node/"+d.Node, currentDocument(d.Expected, d.Fields), d.Expected)
+	}
+	for _, d := range diff.NodeDeletions {
+		r.outputUnifiedDocument("node/"+d.Node, deletedDocument(catalogdiff.ToJSONValue(d.Current)), nil)
+	}
+
+	for _, d := range diff.ServiceAdditions {
+		r.outputUnifiedDocument(serviceDocName(d), nil, d.Expected)
+	}
+	for _, d := range diff.ServiceModifications {
+		r.outputUnifiedDocument(serviceDocName(d), currentDocument(d.Expected, d.Fields), d.Expected)
+	}
+	for _, d := range diff.ServiceDeletions {
+		r.outputUnifiedDocument(serviceDocName(d), deletedDocument(catalogdiff.ToJSONValue(d.Current)), nil)
+	}
+
+	for _, d := range diff.CheckDeletions {
+		r.outputUnifiedDocument(fmt.Sprintf("check/%s/%s", d.Node, d.CheckID), deletedDocument(catalogdiff.ToJSONValue(d.Current)), nil)
+	}
+}
+
+// serviceDocName returns the document name of a service
+func serviceDocName(d catalogdiff.ServiceDiff) string {
+	return fmt.Sprintf("service/%s/%s", d.Node, d.ServiceID)
+}
+
+// outputUnifiedDocument outputs the diff of one document. A nil side is
+// shown as /dev/null, as for added or deleted files.
+func (r *reportWriter) outputUnifiedDocument(name string, current, expected interface{}) {
+	oldName, newName := "consul/"+name, "payload/"+name
+	var oldLines, newLines []string
+
+	if current == nil {
+		oldName = "/dev/null"
+	} else {
+		oldLines = jsonLines(current)
+	}
+	if expected == nil {
+		newName = "/dev/null"
+	} else {
+		newLines = jsonLines(expected)
+	}
+
+	hunks := unifiedHunks(oldLines, newLines, unifiedContext)
+	if len(hunks) == 0 {
+		return
+	}
+
+	r.println(r.colorize(colorBold, "--- "+oldName))
+	r.println(r.colorize(colorBold, "+++ "+newName))
+	for _, h := range hunks {
+		for _, line := range strings.SplitAfter(h, "\n") {
+			r.print(r.colorizeDiffLine(line))
+		}
+	}
+}
+
+// colorizeDiffLine colors a unified diff line by its first character
+func (r *reportWriter) colorizeDiffLine(line string) string {
+	text := strings.TrimSuffix(line, "\n")
+	suffix := line[len(text):]
+
+	switch {
+	case strings.HasPrefix(text, "@@"):
+		return r.colorize(colorCyan, text) + suffix
+	case strings.HasPrefix(text, "+"):
+		return r.colorize(colorGreen, text) + suffix
+	case strings.HasPrefix(text, "-"):
+		return r.colorize(colorRed, text) + suffix
+	default:
+		return r.colorize(colorDim, text) + suffix
+	}
+}
+
+// jsonLines renders a value as pretty-printed JSON lines with sorted keys
+func jsonLines(v interface{}) []string {
+	data, err := json.MarshalIndent(sortTags(catalogdiff.ToJSONValue(v)), "", "  ")
+	if err != nil {
+		return []string{fmt.Sprint(v)}
+	}
+	return strings.Split(string(data), "\n")
+}
+
+// sortTags sorts a top-level Tags array, since tags are compared as a set
+func sortTags(v interface{}) interface{} {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return v
+	}
+
+	if tags, ok := m["Tags"].([]interface{}); ok {
+		sorted := make([]string, len(tags))
+		for i, tag := range tags {
+			sorted[i] = fmt.Sprint(tag)
+		}
+		sort.Strings(sorted)
+		m["Tags"] = sorted
+	}
+	return m
+}
+
+// currentDocument returns the expected document with the differing fields
+// set to their current values, so that the diff shows exactly the
+// differences found by the comparator. Fields compared as equal, such as
+// reordered tags, and ignored fields render the same on both sides.
+func currentDocument(expected interface{}, fields []catalogdiff.FieldDiff) interface{} {
+	doc := catalogdiff.ToJSONValue(expected)
+	for _, f := range fields {
+		doc = setPath(doc, parsePath(f.Field), f.Current)
+	}
+	return doc
+}
+
+// pathStep is a map key or an array index of a field path
+type pathStep struct {
+	key     string
+	index   int
+	isIndex bool
+}
+
+// parsePath splits a field path such as "Proxy.Upstreams[0].LocalBindPort"
+// into steps
+func parsePath(path string) []pathStep {
+	var steps []pathStep
+	for _, segment := range strings.Split(path, ".") {
+		key, rest, _ := strings.Cut(segment, "[")
+		steps = append(steps, pathStep{key: key})
+		for _, index := range strings.Split(rest, "[") {
+			if n, err := strconv.Atoi(strings.TrimSuffix(index, "]")); err == nil {
+				steps = append(steps, pathStep{index: n, isIndex: true})
+			}
+		}
+	}
+	return steps
+}
+
+// setPath sets the value at a path of a JSON document, creating objects and
+// growing arrays as needed. A nil value removes an object key.
+func setPath(doc interface{}, steps []pathStep, value interface{}) interface{} {
+	if len(steps) == 0 {
+		return value
+	}
+	step := steps[0]
+
+	if step.isIndex {
+		arr, _ := doc.([]interface{})
+		for len(arr) <= step.index {
+			arr = append(arr, nil)
+		}
+		arr[step.index] = setPath(arr[step.index], steps[1:], value)
+		return arr
+	}
+
+	m, ok := doc.(map[string]interface{})
+	if !ok {
+		m = make(map[string]interface{})
+	}
+	if len(steps) == 1 && value == nil {
+		delete(m, step.key)
+	} else {
+		m[step.key] = setPath(m[step.key], steps[1:], value)
+	}
+	return m
+}
+
+// deletedDocument returns the current state of a deleted object, without
+// the Raft indexes Consul adds to every object and without empty fields
+func deletedDocument(v interface{}) interface{} {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return v
+	}
+
+	for key, value := range m {
+		if key == "CreateIndex" || key == "ModifyIndex" || catalogdiff.IsZeroValue(value) {
+			delete(m, key)
+		}
+	}
+	return m
+}
+
+// lineEdit represents one line of an edit script
+type lineEdit struct {
+	op   byte // ' ', '-' or '+'
+	text string
+}
+
+// diffLines computes a line edit script from a to b using the longest
+// common subsequence
+func diffLines(a, b []string) []lineEdit {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var edits []lineEdit
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			edits = append(edits, lineEdit{' ', a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			edits = append(edits, lineEdit{'-', a[i]})
+			i++
+		default:
+			edits = append(edits, lineEdit{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		edits = append(edits, lineEdit{'-', a[i]})
+	}
+	for ; j < len(b); j++ {
+		edits = append(edits, lineEdit{'+', b[j]})
+	}
+
+	return edits
+}
+
+// unifiedHunks formats an edit script as unified diff hunks with the given
+// number of context lines
+func unifiedHunks(a, b []string, context int) []string {
+	edits := diffLines(a, b)
+
+	var hunks []string
+	for start := 0; start < len(edits); {
+		// Find the next change
+		for start < len(edits) && edits[start].op == ' ' {
+			start++
+		}
+		if start == len(edits) {
+			break
+		}
+
+		// Extend the hunk while changes are within 2*context lines of each other
+		end := start
+		for k := start; k < len(edits); k++ {
+			if edits[k].op != ' ' {
+				end = k + 1
+			} else if k-end >= 2*context {
+				break
+			}
+		}
+
+		from := max(start-context, 0)
+		to := min(end+context, len(edits))
+		hunks = append(hunks, formatHunk(edits, from, to))
+		start = to
+	}
+
+	return hunks
+}
+
+// formatHunk formats edits[from:to] with a "@@ -l,s +l,s @@" header
+func formatHunk(edits []lineEdit, from, to int) string {
+	oldStart, newStart := 1, 1
+	for _, e := range edits[:from] {
+		if e.op != '+' {
+			oldStart++
+		}
+		if e.op != '-' {
+			newStart++
+		}
+	}
+
+	var body strings.Builder
+	oldCount, newCount := 0, 0
+	for _, e := range edits[from:to] {
+		if e.op != '+' {
+			oldCount++
+		}
+		if e.op != '-' {
+			newCount++
+		}
+		fmt.Fprintf(&body, "%c%s\n", e.op, e.text)
+	}
+
+	// Empty ranges start at the line before, as in diff -u
+	if oldCount == 0 {
+		oldStart--
+	}
+	if newCount == 0 {
+		newStart--
+	}
+
+	return fmt.Sprintf("@@ -%d,%d +%d,%d @@\n%s", oldStart, oldCount, newStart, newCount, body.String())
+}
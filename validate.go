@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/zinrai/consul-catalog-diff/catalogdiff"
+)
+
+// runValidate implements the validate subcommand and returns the exit code
+func runValidate(args []string) int {
+	config := parseValidateConfig(args)
+	setupLogging(Config{})
+
+	operations, err := catalogdiff.ReadOperations(config.File)
+	if err != nil {
+		log.Printf("[ERROR] Failed to load operations: %v", err)
+		return 2
+	}
+
+	violations := catalogdiff.ValidateOperations(operations)
+
+	if config.ConsulAddr != "" {
+		ctx, stop := interruptContext()
+		defer stop()
+
+		src := config.newConsulSource(config.ConsulAddr)
+		src.Logger = log.Default()
+		refViolations, err := catalogdiff.ValidateNodeReferences(ctx, src, operations)
+		if err != nil {
+			log.Printf("[ERROR] Failed to check node references: %v", err)
+			return 2
+		}
+		violations = append(violations, refViolations...)
+	}
+
+	catalogdiff.SortViolations(violations)
+
+	if len(violations) == 0 {
+		fmt.Println("No violations found")
+		return 0
+	}
+
+	for _, v := range violations {
+		fmt.Printf("%s:%d: %s: %s\n", config.File, v.Line, v.Target, v.Message)
+	}
+	fmt.Printf("\n%d violation(s) found\n", len(violations))
+	return 1
+}